@@ -0,0 +1,65 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/giacomoguiulfo/benchio/pkg/bench"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// compareCmd represents the compare command
+var compareCmd = &cobra.Command{
+	Use:   "compare BASELINE RESULT...",
+	Short: "Compare saved benchmark results",
+	Long: `Compare two or more results saved with "benchio run --output json".
+The first file is used as the baseline for the others.`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		names := make([]string, 0, len(args))
+		results := make([]*bench.Result, 0, len(args))
+		for _, path := range args {
+			result, err := bench.LoadResult(path)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			names = append(names, filepath.Base(path))
+			results = append(results, result)
+		}
+		regressions := bench.Compare(os.Stdout, names, results, viper.GetFloat64("threshold"))
+		if regressions > 0 && viper.GetBool("failOnRegression") {
+			os.Exit(2)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(compareCmd)
+	compareCmd.Flags().Float64("threshold", 5, "flag relative changes larger than this percentage")
+	viper.BindPFlag("threshold", compareCmd.Flags().Lookup("threshold"))
+	compareCmd.Flags().Bool("fail-on-regression", false, "exit with status 2 when a regression is flagged")
+	viper.BindPFlag("failOnRegression", compareCmd.Flags().Lookup("fail-on-regression"))
+}
@@ -21,13 +21,13 @@
 package cmd
 
 import (
-	"crypto/rand"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
 	"path/filepath"
 	"time"
 
+	"github.com/giacomoguiulfo/benchio/pkg/bench"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -38,6 +38,8 @@ type createCfg struct {
 	objectNamePrefix string
 	verbose          bool
 	directory        string
+	pattern          bench.DataPattern
+	seed             int64
 }
 
 // createCmd represents the create command
@@ -46,12 +48,20 @@ var createCmd = &cobra.Command{
 	Short: "Creates benchio's workload locally",
 	Long:  ``,
 	Run: func(cmd *cobra.Command, args []string) {
+		pattern, err := bench.NewDataPattern(viper.GetString("dataPattern"),
+			viper.GetFloat64("compressionRatio"), viper.GetFloat64("dedupRatio"))
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 		createWorkload(&createCfg{
 			objectSize:       viper.GetInt64("objectSize"),
 			objectNamePrefix: viper.GetString("objectNamePrefix"),
 			objectCount:      viper.GetSizeInBytes("numSamples"),
 			verbose:          viper.GetBool("verbose"),
 			directory:        viper.GetString("directory"),
+			pattern:          pattern,
+			seed:             viper.GetInt64("seed"),
 		})
 	},
 }
@@ -65,23 +75,16 @@ func init() {
 
 func createWorkload(cfg *createCfg) {
 	if cfg.verbose == true {
-		fmt.Println("Generating in-memory sample data... ")
+		fmt.Printf("Generating %s sample data... \n", cfg.pattern)
 	}
 	timeGenData := time.Now()
-	buffer := make([]byte, cfg.objectSize, cfg.objectSize)
-	_, err := rand.Read(buffer)
-	if err != nil {
-		fmt.Println("Could not allocate a buffer")
-		os.Exit(1)
-	}
 	for i := uint(0); i < cfg.objectCount; i++ {
 		basename := fmt.Sprintf("%s%d", cfg.objectNamePrefix, i)
 		absname := filepath.Join(cfg.directory, basename)
 		if cfg.verbose == true {
 			fmt.Printf("Wrote %d bytes to %s\n", cfg.objectSize, absname)
 		}
-		err := ioutil.WriteFile(absname, buffer, 0755)
-		if err != nil {
+		if err := writeObject(absname, cfg.pattern.Payload(cfg.seed, basename, cfg.objectSize)); err != nil {
 			fmt.Printf("Unable to write file: %v\n", err)
 			os.Exit(1)
 		}
@@ -90,3 +93,15 @@ func createWorkload(cfg *createCfg) {
 		fmt.Printf("Done (%s)\n", time.Since(timeGenData))
 	}
 }
+
+func writeObject(path string, content io.Reader) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, content); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -54,6 +54,14 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&cfgFile, "file", "f", "", "config file (default is $HOME/.benchio.yml)")
 	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "verbose output")
 	viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
+	rootCmd.PersistentFlags().String("data-pattern", "", "content of generated objects: random, zero or text (default random)")
+	viper.BindPFlag("dataPattern", rootCmd.PersistentFlags().Lookup("data-pattern"))
+	rootCmd.PersistentFlags().Float64("compression-ratio", 0, "ratio random content compresses by (e.g. 2)")
+	viper.BindPFlag("compressionRatio", rootCmd.PersistentFlags().Lookup("compression-ratio"))
+	rootCmd.PersistentFlags().Float64("dedup-ratio", 0, "ratio content deduplicates by across objects (e.g. 3)")
+	viper.BindPFlag("dedupRatio", rootCmd.PersistentFlags().Lookup("dedup-ratio"))
+	rootCmd.PersistentFlags().Int64("seed", 0, "seed of the content and sizes generated for objects")
+	viper.BindPFlag("seed", rootCmd.PersistentFlags().Lookup("seed"))
 }
 
 // initConfig reads in config file and ENV variables if set.
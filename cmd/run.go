@@ -21,6 +21,9 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/giacomoguiulfo/benchio/pkg/bench"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -37,10 +40,42 @@ var runCmd = &cobra.Command{
 			AccessKey:        viper.GetString("accessKey"),
 			SecretKey:        viper.GetString("secretKey"),
 			Endpoint:         viper.GetString("endpoint"),
+			Output:           viper.GetString("output"),
+			SampleLog:        viper.GetString("sampleLog"),
+			SampleLogFormat:  viper.GetString("sampleLogFormat"),
+			Precision:        viper.GetInt("histogramPrecision"),
+			HistogramLog:     viper.GetString("histogramLog"),
+			Interval:         viper.GetDuration("interval"),
+			Duration:         viper.GetDuration("duration"),
+			Warmup:           viper.GetDuration("warmup"),
+			WarmupOps:        viper.GetSizeInBytes("warmupOps"),
+			ShowWarmup:       viper.GetBool("showWarmup"),
+			Mix:              viper.GetString("mix"),
+			Sweep:            viper.GetString("sweep"),
+			SweepOp:          viper.GetString("sweepOp"),
+			SweepMinGain:     viper.GetFloat64("sweepMinGain"),
+			SweepMaxP99:      viper.GetDuration("sweepMaxP99"),
+			KeyPattern:       viper.GetString("keyPattern"),
+			RangeSize:        viper.GetInt64("rangeSize"),
+			RangeOffsets:     viper.GetString("rangeOffsets"),
+			DataPattern:      viper.GetString("dataPattern"),
+			CompressionRatio: viper.GetFloat64("compressionRatio"),
+			DedupRatio:       viper.GetFloat64("dedupRatio"),
+			PayloadSigning:   viper.GetString("payloadSigning"),
+			Checksum:         viper.GetString("checksum"),
+			SSE:              viper.GetString("sse"),
+			SSEKMSKeyID:      viper.GetString("sseKmsKeyId"),
+			SSECustomerKey:   viper.GetString("sseCustomerKey"),
+			Verify:           viper.GetBool("verify"),
+			Seed:             viper.GetInt64("seed"),
+			Rate:             viper.GetFloat64("rate"),
+			Arrivals:         viper.GetString("arrivals"),
+			MetricsAddr:      viper.GetString("metricsAddr"),
+			HTMLReport:       viper.GetString("htmlReport"),
 			Bucket:           viper.GetString("bucket"),
 			MultipartSize:    viper.GetInt64("multipartSize"),
 			ObjectSize:       viper.GetInt64("objectSize"),
-			ObjectSplit:      viper.GetSizeInBytes("objectSplit"),
+			ObjectSizes:      viper.GetString("objectSizes"),
 			ObjectNamePrefix: viper.GetString("objectNamePrefix"),
 			Clients:          viper.GetSizeInBytes("numClients"),
 			ObjectCount:      viper.GetSizeInBytes("numSamples"),
@@ -48,12 +83,43 @@ var runCmd = &cobra.Command{
 			Region:           viper.GetString("region"),
 			Write:            viper.GetBool("write"),
 			Read:             viper.GetBool("read"),
+			Head:             viper.GetBool("head"),
+			List:             viper.GetBool("list"),
+			Copy:             viper.GetBool("copy"),
+			Delete:           viper.GetBool("delete"),
 			Cleanup:          viper.GetBool("cleanup"),
 		}
-		bench.Mark(conf)
+		stages, err := loadStages(viper.GetString("workload"))
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		conf.Stages = stages
+		if _, err := bench.Mark(conf); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 	},
 }
 
+// loadStages reads the stages of a workload from the given file, or from
+// the configuration file if none is given
+func loadStages(path string) ([]bench.Stage, error) {
+	v := viper.GetViper()
+	if path != "" {
+		v = viper.New()
+		v.SetConfigFile(path)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("Unable to read workload: %v", err)
+		}
+	}
+	var stages []bench.Stage
+	if err := v.UnmarshalKey("stages", &stages); err != nil {
+		return nil, fmt.Errorf("Unable to read workload stages: %v", err)
+	}
+	return stages, nil
+}
+
 func init() {
 	rootCmd.AddCommand(runCmd)
 	runCmd.Flags().String("endpoint", "", "AWS endpoint (required)")
@@ -64,4 +130,54 @@ func init() {
 	viper.BindPFlag("write", runCmd.Flags().Lookup("write"))
 	runCmd.Flags().BoolP("read", "r", true, "perform read tests")
 	viper.BindPFlag("read", runCmd.Flags().Lookup("read"))
+	runCmd.Flags().Bool("head", false, "perform head tests")
+	viper.BindPFlag("head", runCmd.Flags().Lookup("head"))
+	runCmd.Flags().Bool("list", false, "perform list tests")
+	viper.BindPFlag("list", runCmd.Flags().Lookup("list"))
+	runCmd.Flags().Bool("copy", false, "perform copy tests")
+	viper.BindPFlag("copy", runCmd.Flags().Lookup("copy"))
+	runCmd.Flags().Bool("delete", false, "perform delete tests")
+	viper.BindPFlag("delete", runCmd.Flags().Lookup("delete"))
+	runCmd.Flags().StringP("output", "o", "text", "output format of the results (text or json)")
+	viper.BindPFlag("output", runCmd.Flags().Lookup("output"))
+	runCmd.Flags().String("sample-log", "", "write every completed request to this file")
+	viper.BindPFlag("sampleLog", runCmd.Flags().Lookup("sample-log"))
+	runCmd.Flags().String("histogram-log", "", "save the latency histograms in HdrHistogram log format to this file")
+	viper.BindPFlag("histogramLog", runCmd.Flags().Lookup("histogram-log"))
+	runCmd.Flags().Duration("duration", 0, "run every test for this long instead of numSamples operations (e.g. 30m)")
+	viper.BindPFlag("duration", runCmd.Flags().Lookup("duration"))
+	runCmd.Flags().Duration("warmup", 0, "leave the operations of this long warm-up out of the results of every test (e.g. 30s)")
+	viper.BindPFlag("warmup", runCmd.Flags().Lookup("warmup"))
+	runCmd.Flags().String("mix", "", "run a mixed test with these operation weights (e.g. read=70,write=20,head=5,delete=5)")
+	viper.BindPFlag("mix", runCmd.Flags().Lookup("mix"))
+	runCmd.Flags().String("workload", "", "run the stages of the workload described in this file instead of the default tests")
+	viper.BindPFlag("workload", runCmd.Flags().Lookup("workload"))
+	runCmd.Flags().String("sweep", "", "repeat a test with these numbers of clients to find where throughput saturates (e.g. 1-256 or 1,8,32)")
+	viper.BindPFlag("sweep", runCmd.Flags().Lookup("sweep"))
+	runCmd.Flags().String("sweep-op", "", "operation repeated by the sweep (read by default)")
+	viper.BindPFlag("sweepOp", runCmd.Flags().Lookup("sweep-op"))
+	runCmd.Flags().Duration("sweep-max-p99", 0, "consider the service saturated once the p99 latency exceeds this (e.g. 500ms)")
+	viper.BindPFlag("sweepMaxP99", runCmd.Flags().Lookup("sweep-max-p99"))
+	runCmd.Flags().String("key-pattern", "", "pick the keys to read: sequential, random, zipf:THETA or hotspot:OPS,KEYS (e.g. hotspot:90,10)")
+	viper.BindPFlag("keyPattern", runCmd.Flags().Lookup("key-pattern"))
+	runCmd.Flags().Int64("range-size", 0, "read byte ranges of this many bytes instead of whole objects")
+	viper.BindPFlag("rangeSize", runCmd.Flags().Lookup("range-size"))
+	runCmd.Flags().String("range-offsets", "", "offsets of the byte ranges read: sequential or random")
+	viper.BindPFlag("rangeOffsets", runCmd.Flags().Lookup("range-offsets"))
+	runCmd.Flags().String("payload-signing", "", "how uploaded content is signed: unsigned, signed or streaming (default unsigned)")
+	viper.BindPFlag("payloadSigning", runCmd.Flags().Lookup("payload-signing"))
+	runCmd.Flags().String("checksum", "", "send a checksum of uploaded content: md5, crc32, crc32c, sha1 or sha256")
+	viper.BindPFlag("checksum", runCmd.Flags().Lookup("checksum"))
+	runCmd.Flags().String("sse", "", "server-side encryption of written objects: sse-s3, sse-kms or sse-c")
+	viper.BindPFlag("sse", runCmd.Flags().Lookup("sse"))
+	runCmd.Flags().Bool("verify", false, "write content derived from each key and check it on every read")
+	viper.BindPFlag("verify", runCmd.Flags().Lookup("verify"))
+	runCmd.Flags().Float64("rate", 0, "send requests at this rate (ops/s) regardless of response times")
+	viper.BindPFlag("rate", runCmd.Flags().Lookup("rate"))
+	runCmd.Flags().Duration("interval", 0, "report throughput and latency every interval (e.g. 5s)")
+	viper.BindPFlag("interval", runCmd.Flags().Lookup("interval"))
+	runCmd.Flags().String("metrics-addr", "", "serve Prometheus metrics on this address (e.g. :9100) during the run")
+	viper.BindPFlag("metricsAddr", runCmd.Flags().Lookup("metrics-addr"))
+	runCmd.Flags().String("html", "", "write a self-contained HTML report of the run to this file")
+	viper.BindPFlag("htmlReport", runCmd.Flags().Lookup("html"))
 }
@@ -21,12 +21,13 @@
 package bench
 
 import (
-	"bytes"
-	"crypto/rand"
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"sort"
+	"net/http/httptrace"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -40,37 +41,110 @@ import (
 
 // Config holds the configuration paramters for the Runner type
 type Config struct {
-	AccessKey        string
-	SecretKey        string
-	Region           string
-	Operation        string
-	Clients          uint
-	MultipartSize    int64
-	ObjectSize       int64
-	ObjectSplit      uint
-	ObjectCount      uint
-	ObjectNamePrefix string
-	Bucket           string
-	Endpoint         string
-	Verbose          bool
-	Write            bool
-	Read             bool
-	Cleanup          bool
+	AccessKey        string        `json:"accessKey"`
+	SecretKey        string        `json:"-"`
+	Region           string        `json:"region"`
+	Operation        string        `json:"operation,omitempty"`
+	Clients          uint          `json:"numClients"`
+	MultipartSize    int64         `json:"multipartSize"`
+	ObjectSize       int64         `json:"objectSize"`
+	ObjectSizes      string        `json:"objectSizes,omitempty"`
+	ObjectCount      uint          `json:"numSamples"`
+	ObjectNamePrefix string        `json:"objectNamePrefix"`
+	Bucket           string        `json:"bucket"`
+	Endpoint         string        `json:"endpoint"`
+	Output           string        `json:"output"`
+	SampleLog        string        `json:"sampleLog,omitempty"`
+	SampleLogFormat  string        `json:"sampleLogFormat,omitempty"`
+	Precision        int           `json:"histogramPrecision"`
+	HistogramLog     string        `json:"histogramLog,omitempty"`
+	Interval         time.Duration `json:"interval"`
+	Duration         time.Duration `json:"duration,omitempty"`
+	Warmup           time.Duration `json:"warmup,omitempty"`
+	WarmupOps        uint          `json:"warmupOps,omitempty"`
+	ShowWarmup       bool          `json:"showWarmup,omitempty"`
+	Mix              string        `json:"mix,omitempty"`
+	KeyPattern       string        `json:"keyPattern,omitempty"`
+	RangeSize        int64         `json:"rangeSize,omitempty"`
+	RangeOffsets     string        `json:"rangeOffsets,omitempty"`
+	DataPattern      string        `json:"dataPattern"`
+	CompressionRatio float64       `json:"compressionRatio"`
+	DedupRatio       float64       `json:"dedupRatio"`
+	PayloadSigning   string        `json:"payloadSigning"`
+	Checksum         string        `json:"checksum,omitempty"`
+	SSE              string        `json:"sse,omitempty"`
+	SSEKMSKeyID      string        `json:"sseKmsKeyId,omitempty"`
+	SSECustomerKey   string        `json:"-"`
+	Verify           bool          `json:"verify,omitempty"`
+	Seed             int64         `json:"seed"`
+	Rate             float64       `json:"rate,omitempty"`
+	Arrivals         string        `json:"arrivals,omitempty"`
+	MetricsAddr      string        `json:"metricsAddr,omitempty"`
+	HTMLReport       string        `json:"htmlReport,omitempty"`
+	Stages           []Stage       `json:"stages,omitempty"`
+	Sweep            string        `json:"sweep,omitempty"`
+	SweepOp          string        `json:"sweepOp,omitempty"`
+	SweepMinGain     float64       `json:"sweepMinGain,omitempty"`
+	SweepMaxP99      time.Duration `json:"sweepMaxP99,omitempty"`
+	Verbose          bool          `json:"verbose"`
+	Write            bool          `json:"write"`
+	Read             bool          `json:"read"`
+	Head             bool          `json:"head"`
+	List             bool          `json:"list"`
+	Copy             bool          `json:"copy"`
+	Delete           bool          `json:"delete"`
+	Cleanup          bool          `json:"cleanup"`
 }
 
-type request interface{}
+type request struct {
+	op    string
+	key   string
+	index uint
+	size  int64
+	// offset is where ranged reads start within the object
+	offset int64
+	input  interface{}
+	// intended is when an open-loop request should have been sent
+	intended time.Time
+	// warmup marks the first requests of a test, left out of its results
+	// by warmupOps
+	warmup bool
+}
 
 type response struct {
+	op       string
+	key      string
+	index    uint
+	size     int64
+	endpoint string
+	client   uint
+	start    time.Time
 	err      error
 	duration time.Duration
-	bytes    int64
+	// ttfb is the time to first byte of reads, or 0 if it wasn't measured
+	ttfb time.Duration
+	// corrected is the time since the intended send time of open-loop
+	// requests, or 0 for closed-loop ones
+	corrected time.Duration
+	bytes     int64
+	warmup    bool
 }
 
 type report struct {
 	Operation        string
 	bytesTransmitted int64
 	numErrors        int
-	opDurations      []float64
+	latency          *latency
+	ttfb             *latency
+	corrected        *latency
+	warmup           *stats
+	warmupDuration   time.Duration
+	endpoints        map[string]*stats
+	mix              map[string]*stats
+	sizes            sizeBuckets
+	errors           errorClasses
+	intervals        []Interval
+	startTime        time.Time
 	totalDuration    time.Duration
 }
 
@@ -80,53 +154,169 @@ type Runner struct {
 	endpoints []string
 	requests  chan request
 	responses chan response
+	// keys is the number of objects available for reading
+	keys   uint
+	mix    mix
+	keySet *keySet
+	// keyPattern picks the keys of reads, nil walking them in order
+	keyPattern *keyPattern
+	// sizes holds the size of every key when objectSizes is set
+	sizes   []int64
+	samples sampleWriter
+	metrics *metrics
+	// total aggregates the responses of every stage of a workload
+	total *stats
+	out   io.Writer
 }
 
 const (
 	readOp     = "Read"
 	writeOp    = "Write"
+	headOp     = "Head"
+	deleteOp   = "Delete"
+	listOp     = "List"
+	copyOp     = "Copy"
+	mixedOp    = "Mixed"
 	commitSize = 1000
+	// copySuffix is appended to the keys of objects created by copies
+	copySuffix = "-copy"
+	// histogramBuckets is the number of buckets of the latency
+	// distribution included in the results
+	histogramBuckets = 50
 )
 
-type RepeatReader struct {
-	reader        io.ReadSeeker
-	numBytes      int64
-	numRepeat     uint
-	currentRepeat uint
-}
+const (
+	textOutput = "text"
+	jsonOutput = "json"
+)
+
+// percentiles lists the latency percentiles included in every report
+var percentiles = []float64{100, 99, 90, 75, 50, 25, 0}
+
+// phases lists the operations of a run without stages, in order
+var phases = []string{writeOp, readOp, headOp, listOp, copyOp, mixedOp, deleteOp}
 
 type DiscardAt struct {
 	writer io.Writer
 }
 
-// Mark performs a benchmark test on the configured service
-func Mark(conf *Config) error {
+// Mark performs a benchmark test on the configured service and returns
+// the collected results
+func Mark(conf *Config) (*Result, error) {
 	if err := conf.validate(); err != nil {
-		return err
+		return nil, err
 	}
 	awsCfg := &aws.Config{
 		Credentials:      credentials.NewStaticCredentials(conf.AccessKey, conf.SecretKey, ""),
 		Region:           aws.String(conf.Region),
 		S3ForcePathStyle: aws.Bool(true),
+		// Otherwise the SDK sends a Content-MD5 with every upload
+		S3DisableContentMD5Validation: aws.Bool(conf.Checksum != "md5"),
 	}
 	runner := Runner{
 		conf:      conf,
 		requests:  make(chan request),
 		responses: make(chan response),
 		endpoints: strings.Split(conf.Endpoint, ","),
+		keys:      conf.ObjectCount,
+		out:       os.Stdout,
 	}
-	fmt.Println(runner)
-	bufferBytes, err := generateSampleData(conf.ObjectSize / int64(conf.ObjectSplit))
-	if err != nil {
-		return err
+	if conf.Output == jsonOutput {
+		// Keep stdout clean for the machine-readable results
+		runner.out = os.Stderr
 	}
-	runner.prepare(awsCfg)
-	writeReport := runner.run(writeOp, bufferBytes)
-	readReport := runner.run(readOp, bufferBytes)
-	runner.cleanup(awsCfg)
-	fmt.Println(writeReport)
-	fmt.Println(readReport)
-	return nil
+	if conf.Mix != "" {
+		mix, err := parseMix(conf.Mix)
+		if err != nil {
+			return nil, err
+		}
+		runner.mix = mix
+	}
+	if conf.KeyPattern != "" {
+		pattern, err := parseKeyPattern(conf.KeyPattern)
+		if err != nil {
+			return nil, err
+		}
+		runner.keyPattern = pattern
+	}
+	if conf.SampleLog != "" {
+		samples, err := newSampleWriter(conf.SampleLog, conf.SampleLogFormat)
+		if err != nil {
+			return nil, err
+		}
+		runner.samples = samples
+	}
+	if conf.MetricsAddr != "" {
+		metrics, err := newMetrics(conf.MetricsAddr)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to serve metrics: %v", err)
+		}
+		defer metrics.close()
+		runner.metrics = metrics
+	}
+	result := &Result{Config: conf, Parameters: runner.String(), StartTime: time.Now()}
+	fmt.Fprintln(runner.out, runner)
+	var reports []report
+	if len(conf.Stages) > 0 {
+		var err error
+		if reports, err = runner.runStages(awsCfg); err != nil {
+			return nil, err
+		}
+	} else if conf.Sweep != "" {
+		var err error
+		if reports, result.Sweep, err = runner.runSweep(awsCfg); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := runner.loadSizes(); err != nil {
+			return nil, err
+		}
+		runner.prepare(awsCfg)
+		for _, op := range phases {
+			if runner.enabled(op) {
+				reports = append(reports, runner.run(op))
+			}
+		}
+		runner.cleanup(awsCfg)
+	}
+	result.EndTime = time.Now()
+	if conf.HistogramLog != "" {
+		if err := writeHistogramLog(conf.HistogramLog, result.StartTime, reports); err != nil {
+			return nil, fmt.Errorf("Unable to write histogram log: %v", err)
+		}
+	}
+	if runner.samples != nil {
+		if err := runner.samples.close(); err != nil {
+			return nil, fmt.Errorf("Unable to write sample log: %v", err)
+		}
+	}
+	var duration time.Duration
+	for _, report := range reports {
+		result.Operations = append(result.Operations, report.result())
+		duration += report.totalDuration
+	}
+	if runner.total != nil {
+		summary := runner.total.breakdown("Total", duration)
+		result.Summary = &summary
+	}
+	if conf.HTMLReport != "" {
+		if err := writeHTMLReport(conf.HTMLReport, result); err != nil {
+			return nil, fmt.Errorf("Unable to write HTML report: %v", err)
+		}
+	}
+	if conf.Output == jsonOutput {
+		return result, result.WriteJSON(os.Stdout)
+	}
+	for _, report := range reports {
+		fmt.Println(report)
+	}
+	if result.Sweep != nil {
+		result.Sweep.write(os.Stdout)
+	}
+	if runner.total != nil {
+		fmt.Printf("Combined summary of %d stages: %s, %0.4f MB/s\n", len(reports), runner.total, result.Summary.Throughput)
+	}
+	return result, nil
 }
 
 func (conf *Config) validate() error {
@@ -136,162 +326,579 @@ func (conf *Config) validate() error {
 	if conf.Endpoint == "" {
 		return fmt.Errorf("You need to specify one or more endpoints")
 	}
-	return nil
-}
-
-func generateSampleData(size int64) ([]byte, error) {
-	fmt.Printf("Generating in-memory sample data... ")
-	timeGenData := time.Now()
-	buffer := make([]byte, size, size)
-	_, err := rand.Read(buffer)
+	if conf.Output == "" {
+		conf.Output = textOutput
+	}
+	if conf.Output != textOutput && conf.Output != jsonOutput {
+		return fmt.Errorf("output(%s) needs to be either %q or %q", conf.Output, textOutput, jsonOutput)
+	}
+	if conf.Precision == 0 {
+		conf.Precision = defaultPrecision
+	}
+	if conf.Precision < 1 || conf.Precision > 5 {
+		return fmt.Errorf("histogramPrecision(%d) needs to be between 1 and 5", conf.Precision)
+	}
+	if conf.Rate < 0 {
+		return fmt.Errorf("rate(%g) needs to be greater than or equal to 0", conf.Rate)
+	}
+	if conf.Rate > 0 && conf.Arrivals == "" {
+		conf.Arrivals = constantArrivals
+	}
+	if conf.Arrivals != "" && conf.Arrivals != constantArrivals && conf.Arrivals != poissonArrivals {
+		return fmt.Errorf("arrivals(%s) needs to be either %q or %q", conf.Arrivals, constantArrivals, poissonArrivals)
+	}
+	if conf.RangeSize < 0 {
+		return fmt.Errorf("rangeSize(%d) needs to be greater than or equal to 0", conf.RangeSize)
+	}
+	if conf.RangeSize > 0 && conf.RangeOffsets == "" {
+		conf.RangeOffsets = sequentialRanges
+	}
+	if conf.RangeOffsets != "" && conf.RangeOffsets != sequentialRanges && conf.RangeOffsets != randomRanges {
+		return fmt.Errorf("rangeOffsets(%s) needs to be either %q or %q", conf.RangeOffsets, sequentialRanges, randomRanges)
+	}
+	if conf.ObjectSizes != "" {
+		if _, err := parseSizeDistribution(conf.ObjectSizes); err != nil {
+			return err
+		}
+	}
+	if err := validateStages(conf.Stages); err != nil {
+		return err
+	}
+	if conf.Sweep != "" {
+		if err := conf.validateSweep(); err != nil {
+			return err
+		}
+	}
+	pattern, err := NewDataPattern(conf.DataPattern, conf.CompressionRatio, conf.DedupRatio)
 	if err != nil {
-		return nil, fmt.Errorf("Could not allocate a buffer")
+		return err
+	}
+	conf.DataPattern, conf.CompressionRatio, conf.DedupRatio = pattern.Kind, pattern.Compression, pattern.Dedup
+	if conf.PayloadSigning == "" {
+		conf.PayloadSigning = unsignedPayload
+	}
+	if conf.PayloadSigning != unsignedPayload && conf.PayloadSigning != signedPayload && conf.PayloadSigning != streamingPayload {
+		return fmt.Errorf("payloadSigning(%s) needs to be one of %q, %q or %q", conf.PayloadSigning, unsignedPayload, signedPayload, streamingPayload)
 	}
-	fmt.Printf("Done (%s)\n", time.Since(timeGenData))
-	return buffer, nil
+	if _, ok := checksumHeaders[conf.Checksum]; conf.Checksum != "" && !ok {
+		return fmt.Errorf("checksum(%s) needs to be one of md5, crc32, crc32c, sha1 or sha256", conf.Checksum)
+	}
+	if conf.Checksum != "" && conf.Checksum != "md5" && conf.MultipartSize > 0 {
+		// Multipart uploads would need to be created for the algorithm, and
+		// completed with the checksum of every part
+		return fmt.Errorf("checksum(%s) can't be used with multipartSize, only md5 can", conf.Checksum)
+	}
+	if err := conf.validateEncryption(); err != nil {
+		return err
+	}
+	if conf.Warmup < 0 {
+		return fmt.Errorf("warmup(%s) needs to be greater than or equal to 0", conf.Warmup)
+	}
+	if conf.Warmup > 0 && conf.WarmupOps > 0 {
+		return fmt.Errorf("Only one of warmup(%s) and warmupOps(%d) can be set", conf.Warmup, conf.WarmupOps)
+	}
+	if conf.Duration == 0 && conf.WarmupOps >= conf.ObjectCount {
+		return fmt.Errorf("warmupOps(%d) needs to be less than numSamples(%d)", conf.WarmupOps, conf.ObjectCount)
+	}
+	if conf.SampleLog != "" && conf.SampleLogFormat == "" {
+		conf.SampleLogFormat = csvSamples
+		if ext := filepath.Ext(conf.SampleLog); ext == ".jsonl" || ext == ".json" {
+			conf.SampleLogFormat = jsonlSamples
+		}
+	}
+	if conf.SampleLogFormat != "" && conf.SampleLogFormat != csvSamples && conf.SampleLogFormat != jsonlSamples {
+		return fmt.Errorf("sampleLogFormat(%s) needs to be either %q or %q", conf.SampleLogFormat, csvSamples, jsonlSamples)
+	}
+	return nil
 }
 
 func (r *Runner) prepare(cfg *aws.Config) {
 	for i := uint(0); i < r.conf.Clients; i++ {
-		cfg.Endpoint = aws.String(r.endpoints[i%uint(len(r.endpoints))])
-		go r.startClient(cfg)
+		// Every client gets its own copy so the endpoint isn't overwritten
+		// before the goroutine creates its session
+		clientCfg := cfg.Copy().WithEndpoint(r.endpoints[i%uint(len(r.endpoints))])
+		go r.startClient(i, clientCfg, r.conf, r.requests)
 	}
 }
 
-func (r *Runner) startClient(cfg *aws.Config) {
+// startClient sends the requests it receives with its own session. The
+// configuration and requests are passed in, as the runner moves on to those
+// of the next stage while clients may still be starting.
+func (r *Runner) startClient(index uint, cfg *aws.Config, conf *Config, requests <-chan request) {
+	endpoint := aws.StringValue(cfg.Endpoint)
 	session := session.New(cfg)
 	client := s3.New(session)
 	var uploader *s3manager.Uploader
 	var downloader *s3manager.Downloader
-	if r.conf.MultipartSize > 0 {
+	if conf.MultipartSize > 0 {
 		uploader = s3manager.NewUploader(session, func(u *s3manager.Uploader) {
 			u.S3 = client
 			u.Concurrency = 1
 			u.LeavePartsOnError = true
-			u.PartSize = r.conf.MultipartSize
+			u.PartSize = conf.MultipartSize
+			u.RequestOptions = append(u.RequestOptions, conf.payloadOption)
 		})
 		downloader = s3manager.NewDownloader(session, func(d *s3manager.Downloader) {
 			d.S3 = client
 			d.Concurrency = 1
-			d.PartSize = r.conf.MultipartSize
+			d.PartSize = conf.MultipartSize
 		})
 	}
-	for request := range r.requests {
+	for request := range requests {
 		startTime := time.Now()
-		bytes := r.conf.ObjectSize
+		bytes := request.size
+		var ttfb time.Duration
 		var err error
-		switch reqType := request.(type) {
+		switch reqType := request.input.(type) {
 		case *s3.PutObjectInput:
 			req, _ := client.PutObjectRequest(reqType)
-			req.HTTPRequest.Header.Add("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+			req.ApplyOptions(conf.payloadOption)
 			err = req.Send()
 		case *s3manager.UploadInput:
 			_, err = uploader.Upload(reqType)
 		case *s3.GetObjectInput:
-			if r.conf.MultipartSize > 0 {
+			// Multipart downloads measure the first byte of their first part
+			var firstByte time.Time
+			ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+				GotFirstResponseByte: func() {
+					if firstByte.IsZero() {
+						firstByte = time.Now()
+					}
+				},
+			})
+			if conf.MultipartSize > 0 {
 				var writer io.WriterAt = &DiscardAt{ioutil.Discard}
-				bytes, err = downloader.Download(writer, reqType)
+				if conf.Verify {
+					writer = newVerifier(conf.pattern(), conf.Seed, request.key, request.offset)
+				}
+				if bytes, err = downloader.DownloadWithContext(ctx, writer, reqType); err == nil {
+					ttfb = firstByte.Sub(startTime)
+				}
 			} else {
 				req, resp := client.GetObjectRequest(reqType)
+				req.SetContext(ctx)
 				if err = req.Send(); err == nil {
-					bytes, err = io.Copy(ioutil.Discard, resp.Body)
+					ttfb = firstByte.Sub(startTime)
+					writer := ioutil.Discard
+					if conf.Verify {
+						writer = newVerifier(conf.pattern(), conf.Seed, request.key, request.offset)
+					}
+					bytes, err = io.Copy(writer, resp.Body)
+					resp.Body.Close()
 				} else {
 					bytes = 0
 				}
 			}
-			if bytes != r.conf.ObjectSize {
-				err = fmt.Errorf("Expected object length %d, actual %d", r.conf.ObjectSize, bytes)
+			if err == nil && bytes != request.size {
+				err = lengthError{request.size, bytes}
+			}
+		case *s3.HeadObjectInput:
+			req, out := client.HeadObjectRequest(reqType)
+			bytes = 0
+			if err = req.Send(); err == nil && aws.Int64Value(out.ContentLength) != request.size {
+				err = lengthError{request.size, aws.Int64Value(out.ContentLength)}
+			}
+		case *s3.DeleteObjectInput:
+			req, _ := client.DeleteObjectRequest(reqType)
+			bytes = 0
+			err = req.Send()
+		case *s3.ListObjectsV2Input:
+			req, _ := client.ListObjectsV2Request(reqType)
+			bytes = 0
+			err = req.Send()
+		case *s3.CopyObjectInput:
+			// The copied bytes never go through the client but are
+			// still counted, so that throughput reflects the copy
+			req, _ := client.CopyObjectRequest(reqType)
+			if err = req.Send(); err != nil {
+				bytes = 0
 			}
 		default:
 			panic("Unexpected error")
 		}
-		r.responses <- response{
-			err,
-			time.Since(startTime),
-			bytes,
+		var corrected time.Duration
+		if !request.intended.IsZero() {
+			corrected = time.Since(request.intended)
+		}
+		resp := response{
+			op:        request.op,
+			key:       request.key,
+			index:     request.index,
+			size:      request.size,
+			endpoint:  endpoint,
+			client:    index,
+			start:     startTime,
+			err:       err,
+			duration:  time.Since(startTime),
+			ttfb:      ttfb,
+			corrected: corrected,
+			bytes:     bytes,
+			warmup:    request.warmup,
 		}
+		if r.metrics != nil {
+			r.metrics.observe(resp)
+		}
+		r.responses <- resp
 	}
 }
 
-func (r *Runner) run(op string, bufferBytes []byte) report {
-	if (r.conf.Write == false && op == writeOp) ||
-		(r.conf.Read == false && op == readOp) {
-		return report{}
+// enabled returns whether the phase of op is part of a run without stages
+func (r *Runner) enabled(op string) bool {
+	switch op {
+	case writeOp:
+		return r.conf.Write
+	case readOp:
+		return r.conf.Read
+	case headOp:
+		return r.conf.Head
+	case listOp:
+		return r.conf.List
+	case copyOp:
+		return r.conf.Copy
+	case deleteOp:
+		return r.conf.Delete
+	case mixedOp:
+		return r.mix != nil
+	}
+	return false
+}
+
+func (r *Runner) run(op string) report {
+	if op == mixedOp {
+		r.keySet = newKeySet(r.keys, r.conf.ObjectCount)
 	}
 	startTime := time.Now()
-	fmt.Printf("Running %s test...\n", op)
-	go r.submitLoad(op, bufferBytes)
-	report := report{opDurations: make([]float64, 0, r.conf.ObjectCount), Operation: op}
-	for i := uint(0); i < r.conf.ObjectCount; i++ {
-		resp := <-r.responses
+	fmt.Fprintf(r.out, "Running %s test...\n", op)
+	submitted := make(chan uint, 1)
+	go r.submitLoad(op, submitted)
+	report := report{
+		Operation: op,
+		latency:   newLatency(r.conf.Precision),
+		ttfb:      newLatency(r.conf.Precision),
+		corrected: newLatency(r.conf.Precision),
+		endpoints: make(map[string]*stats),
+		mix:       make(map[string]*stats),
+		sizes:     make(sizeBuckets),
+		errors:    make(errorClasses),
+		// Operations started before startTime are part of the warm-up
+		startTime: startTime.Add(r.conf.Warmup),
+	}
+	// warming holds until the responses of every warm-up request are in.
+	// Requests sent after them may complete first, and the results start
+	// with the earliest of those.
+	warming := r.conf.WarmupOps > 0
+	warmups := uint(0)
+	var first time.Time
+	if r.conf.ShowWarmup {
+		report.warmup = newStats(r.conf.Precision)
+	}
+	var ticks <-chan time.Time
+	if period := r.intervalPeriod(); period > 0 {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+	window := newWindow(report.startTime, r.conf.Precision)
+	// The number of requests is only known once submitLoad is done
+	total, done := uint(0), false
+	for i := uint(0); !done || i < total; {
+		var resp response
+		select {
+		case total = <-submitted:
+			done = true
+			continue
+		case now := <-ticks:
+			if warming || now.Before(report.startTime) {
+				continue
+			}
+			r.reportInterval(&report, window, now)
+			window = newWindow(now, r.conf.Precision)
+			continue
+		case resp = <-r.responses:
+			i++
+		}
+		warmup := resp.warmup || (r.conf.Warmup > 0 && resp.start.Before(report.startTime))
+		if r.samples != nil {
+			r.samples.write(resp, warmup)
+		}
+		if warmup {
+			if report.warmup != nil {
+				report.warmup.add(resp)
+			}
+			if op == mixedOp {
+				r.keySet.completed(resp)
+			}
+			if resp.warmup {
+				warmups++
+			}
+			if warming && warmups >= r.conf.WarmupOps {
+				warming = false
+				report.startTime = time.Now()
+				if !first.IsZero() {
+					report.startTime = first
+				}
+				window.start = report.startTime
+			}
+			continue
+		}
+		if r.conf.WarmupOps > 0 && (first.IsZero() || resp.start.Before(first)) {
+			first = resp.start
+		}
+		window.add(resp)
+		if r.total != nil {
+			r.total.add(resp)
+		}
+		statsFor(report.endpoints, resp.endpoint, r.conf.Precision).add(resp)
+		if r.sizes != nil {
+			report.sizes.add(resp, r.conf.Precision)
+		}
+		if op == mixedOp {
+			statsFor(report.mix, resp.op, r.conf.Precision).add(resp)
+			r.keySet.completed(resp)
+		}
 		errorString := ""
 		if resp.err != nil {
 			report.numErrors++
+			report.errors.add(resp.err)
 			errorString = fmt.Sprintf(", error: %s", resp.err)
 		} else {
-			report.bytesTransmitted += r.conf.ObjectSize
-			report.opDurations = append(report.opDurations, resp.duration.Seconds())
+			report.bytesTransmitted += resp.bytes
+			report.latency.record(resp.duration)
+			if resp.ttfb > 0 {
+				report.ttfb.record(resp.ttfb)
+			}
+			if resp.corrected > 0 {
+				report.corrected.record(resp.corrected)
+			}
 		}
 		if r.conf.Verbose {
-			fmt.Printf("%v Operation completed in %0.2fs (%d/%d) - %0.2fMB/s%s\n",
-				op, resp.duration.Seconds(), i+1, r.conf.ObjectCount,
+			progress := fmt.Sprintf("%d/%d", i, r.conf.ObjectCount)
+			if r.conf.Duration > 0 {
+				progress = fmt.Sprintf("%d, %s left", i, r.conf.Duration-time.Since(report.startTime).Truncate(time.Second))
+			}
+			fmt.Fprintf(r.out, "%v Operation completed in %0.2fs (%s) - %0.2fMB/s%s\n",
+				op, resp.duration.Seconds(), progress,
 				(float64(report.bytesTransmitted)/(1024*1024))/time.Since(startTime).Seconds(),
 				errorString)
 		}
 	}
-	report.totalDuration = time.Since(startTime)
-	sort.Float64s(report.opDurations)
+	endTime := time.Now()
+	if !warming && !first.IsZero() && first.Before(report.startTime) {
+		report.startTime = first
+	}
+	if warming || !endTime.After(report.startTime) {
+		// The phase ended before its warm-up did
+		report.startTime = startTime
+	}
+	report.warmupDuration = report.startTime.Sub(startTime)
+	report.totalDuration = endTime.Sub(report.startTime)
+	if op == writeOp && total > 0 && total < r.keys {
+		// A time-bounded write may not get through every key
+		r.keys = total
+	}
+	// The last window is empty if the phase ended during the warm-up
+	if ticks != nil && !warming && endTime.After(window.start) {
+		r.reportInterval(&report, window, endTime)
+	}
 	return report
 }
 
-func (r *Runner) submitLoad(op string, bufferBytes []byte) {
-	Bucket := aws.String(r.conf.Bucket)
-	for i := uint(0); i < r.conf.ObjectCount; i++ {
-		key := aws.String(fmt.Sprintf("%s%d", r.conf.ObjectNamePrefix, i))
-		if op == writeOp {
-			if r.conf.MultipartSize > 0 {
-				r.requests <- &s3manager.UploadInput{
-					Bucket: Bucket,
-					Key:    key,
-					Body:   bytes.NewReader(bufferBytes),
-				}
-			} else {
-        reader := RepeatReader{bytes.NewReader(bufferBytes), int64(len(bufferBytes)), r.conf.ObjectSplit, 0}
-			  r.requests <- &s3.PutObjectInput{
-				  Bucket: Bucket,
-				  Key:    key,
-				  Body:   &reader,
-				}
+// intervalPeriod returns how often intervals are recorded. The HTML report
+// needs them for its charts, so they are recorded every second even if
+// interval reports weren't requested.
+func (r *Runner) intervalPeriod() time.Duration {
+	if r.conf.Interval == 0 && r.conf.HTMLReport != "" {
+		return time.Second
+	}
+	return r.conf.Interval
+}
+
+// reportInterval adds the summary of the given window to the report and
+// prints it if interval reports were requested
+func (r *Runner) reportInterval(report *report, w *window, now time.Time) {
+	interval := w.interval(now)
+	report.intervals = append(report.intervals, interval)
+	if r.conf.Interval > 0 {
+		fmt.Fprintln(r.out, interval.format(report.Operation, now.Sub(report.startTime)))
+	}
+}
+
+// submitLoad sends the requests of the given operation to the clients and
+// then the number of requests it sent to submitted. Time-bounded runs cycle
+// over the available keys until the deadline.
+func (r *Runner) submitLoad(op string, submitted chan<- uint) {
+	var deadline <-chan time.Time
+	if r.conf.Duration > 0 {
+		// The warm-up comes on top of the measured duration
+		timer := time.NewTimer(r.conf.Duration + r.conf.Warmup)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	keys := r.keys
+	if op == writeOp {
+		keys = r.conf.ObjectCount
+	}
+	rng := newRand()
+	var chooser keyChooser
+	if r.keyPattern != nil && op != writeOp && op != deleteOp {
+		if op == mixedOp {
+			chooser = r.keyPattern.chooser(r.conf.ObjectCount)
+		} else {
+			chooser = r.keyPattern.chooser(keys)
+		}
+	}
+	var ranges *rangeCursor
+	if r.conf.RangeSize > 0 {
+		ranges = newRangeCursor(r.conf.RangeSize, r.conf.RangeOffsets)
+	}
+	intended := time.Now()
+	count := uint(0)
+	for ; r.conf.Duration > 0 || count < r.conf.ObjectCount; count++ {
+		requestOp, index := op, count%keys
+		if op == mixedOp {
+			requestOp, index = r.keySet.next(r.mix.pick(rng), rng, chooser)
+		} else if chooser != nil {
+			index = chooser.next(rng)
+		}
+		req := r.newRequest(requestOp, index)
+		req.warmup = count < r.conf.WarmupOps
+		if ranges != nil {
+			ranges.setRange(&req, rng)
+		}
+		if r.conf.Rate > 0 {
+			// Open-loop requests follow their schedule regardless of how
+			// fast the clients are, and their latency is measured from
+			// the intended send time
+			intended = intended.Add(r.nextArrival(rng))
+			req.intended = intended
+			select {
+			case <-time.After(time.Until(intended)):
+			case <-deadline:
+				submitted <- count
+				return
 			}
-		} else if op == readOp {
-			r.requests <- &s3.GetObjectInput{
+		}
+		select {
+		case r.requests <- req:
+		case <-deadline:
+			submitted <- count
+			return
+		}
+	}
+	submitted <- count
+}
+
+func (r *Runner) newRequest(op string, index uint) request {
+	Bucket := aws.String(r.conf.Bucket)
+	key := fmt.Sprintf("%s%d", r.conf.ObjectNamePrefix, index)
+	size := r.sizeOf(index)
+	var input interface{}
+	if op == writeOp {
+		// Every object gets its own content, generated as it is sent
+		body := newPayload(r.conf.pattern(), r.conf.Seed, key, size)
+		if r.conf.MultipartSize > 0 {
+			input = &s3manager.UploadInput{
 				Bucket: Bucket,
-				Key:    key,
+				Key:    aws.String(key),
+				Body:   body,
 			}
 		} else {
-			panic("Invalid Operation")
+			input = &s3.PutObjectInput{
+				Bucket: Bucket,
+				Key:    aws.String(key),
+				Body:   body,
+			}
+		}
+	} else if op == readOp {
+		input = &s3.GetObjectInput{
+			Bucket: Bucket,
+			Key:    aws.String(key),
+		}
+	} else if op == headOp {
+		input = &s3.HeadObjectInput{
+			Bucket: Bucket,
+			Key:    aws.String(key),
+		}
+	} else if op == deleteOp {
+		input = &s3.DeleteObjectInput{
+			Bucket: Bucket,
+			Key:    aws.String(key),
 		}
+	} else if op == listOp {
+		// Each list reads one page of keys, starting at the chosen one
+		input = &s3.ListObjectsV2Input{
+			Bucket:     Bucket,
+			Prefix:     aws.String(r.conf.ObjectNamePrefix),
+			StartAfter: aws.String(key),
+		}
+	} else if op == copyOp {
+		input = &s3.CopyObjectInput{
+			Bucket:     Bucket,
+			Key:        aws.String(key + copySuffix),
+			CopySource: aws.String(r.conf.Bucket + "/" + key),
+		}
+	} else {
+		panic("Invalid Operation")
 	}
+	r.encrypt(input)
+	return request{op: op, key: key, index: index, size: size, input: input}
+}
+
+// sizeOf returns the size of the object with the given key index
+func (r *Runner) sizeOf(index uint) int64 {
+	if index >= uint(len(r.sizes)) {
+		return r.conf.ObjectSize
+	}
+	return r.sizes[index]
+}
+
+// loadSizes draws the size of every object when objectSizes is set
+func (r *Runner) loadSizes() error {
+	r.sizes = nil
+	if r.conf.ObjectSizes == "" {
+		return nil
+	}
+	dist, err := parseSizeDistribution(r.conf.ObjectSizes)
+	if err != nil {
+		return err
+	}
+	// Reads may target keys written by an earlier stage with more of them
+	count := r.conf.ObjectCount
+	if r.keys > count {
+		count = r.keys
+	}
+	r.sizes = drawSizes(dist, r.conf.Seed, count)
+	return nil
 }
 
 func (r *Runner) cleanup(awsCfg *aws.Config) {
 	if r.conf.Cleanup == false {
 		return
 	}
-	fmt.Printf("Cleaning up %d objects...\n", r.conf.ObjectCount)
+	keys := make([]string, 0, r.conf.ObjectCount)
+	for i := uint(0); i < r.conf.ObjectCount; i++ {
+		keys = append(keys, fmt.Sprintf("%s%d", r.conf.ObjectNamePrefix, i))
+	}
+	if r.copies() {
+		for i := uint(0); i < r.conf.ObjectCount; i++ {
+			keys = append(keys, fmt.Sprintf("%s%d%s", r.conf.ObjectNamePrefix, i, copySuffix))
+		}
+	}
+	fmt.Fprintf(r.out, "Cleaning up %d objects...\n", len(keys))
 	startTime := time.Now()
-	client := s3.New(session.New(), awsCfg)
+	// awsCfg has no endpoint, as every client sets its own
+	client := s3.New(session.New(), awsCfg.Copy().WithEndpoint(r.endpoints[0]))
 
 	deletedObjects := 0
 
 	keyList := make([]*s3.ObjectIdentifier, 0, commitSize)
-	for i := 0; uint(i) < r.conf.ObjectCount; i++ {
+	for i := 0; i < len(keys); i++ {
 		key := s3.ObjectIdentifier{
-			Key: aws.String(fmt.Sprintf("%s%d", r.conf.ObjectNamePrefix, i)),
+			Key: aws.String(keys[i]),
 		}
 		keyList = append(keyList, &key)
-		if len(keyList) == commitSize || i == int(r.conf.ObjectCount)-1 {
-			fmt.Printf("Deleting a batch of %d objects in range {%d, %d}... ", len(keyList), i-len(keyList)+1, i)
+		if len(keyList) == commitSize || i == len(keys)-1 {
+			fmt.Fprintf(r.out, "Deleting a batch of %d objects in range {%d, %d}... ", len(keyList), i-len(keyList)+1, i)
 			params := &s3.DeleteObjectsInput{
 				Bucket: aws.String(r.conf.Bucket),
 				Delete: &s3.Delete{
@@ -299,14 +906,27 @@ func (r *Runner) cleanup(awsCfg *aws.Config) {
 			_, err := client.DeleteObjects(params)
 			if err == nil {
 				deletedObjects += len(keyList)
-				fmt.Println("Succeeded")
+				fmt.Fprintln(r.out, "Succeeded")
 			} else {
-				fmt.Printf("Failed (%v)\n", err)
+				fmt.Fprintf(r.out, "Failed (%v)\n", err)
 			}
 			keyList = keyList[:0]
 		}
 	}
-	fmt.Printf("Successfully deleted %d/%d objects in %s\n", deletedObjects, r.conf.ObjectCount, time.Since(startTime))
+	fmt.Fprintf(r.out, "Successfully deleted %d/%d objects in %s\n", deletedObjects, len(keys), time.Since(startTime))
+}
+
+// copies returns whether copy operations may have created objects
+func (r *Runner) copies() bool {
+	if r.conf.Copy {
+		return true
+	}
+	for _, w := range r.mix {
+		if w.op == copyOp && w.weight > 0 {
+			return true
+		}
+	}
+	return false
 }
 
 func (r Runner) String() string {
@@ -314,11 +934,67 @@ func (r Runner) String() string {
 	output += fmt.Sprintf("Endpoint(s):      %s\n", r.endpoints)
 	output += fmt.Sprintf("Bucket:           %s\n", r.conf.Bucket)
 	output += fmt.Sprintf("ObjectNamePrefix: %s\n", r.conf.ObjectNamePrefix)
-	output += fmt.Sprintf("ObjectSize:       %0.4f MB\n", float64(r.conf.ObjectSize)/(1024*1024))
-	output += fmt.Sprintf("ObjectSplit:      %d\n", r.conf.ObjectSplit)
+	if r.conf.ObjectSizes != "" {
+		output += fmt.Sprintf("ObjectSizes:      %s\n", r.conf.ObjectSizes)
+	} else {
+		output += fmt.Sprintf("ObjectSize:       %0.4f MB\n", float64(r.conf.ObjectSize)/(1024*1024))
+	}
 	output += fmt.Sprintf("MultipartSize:    %0.4f MB\n", float64(r.conf.MultipartSize)/(1024*1024))
 	output += fmt.Sprintf("numClients:       %d\n", r.conf.Clients)
 	output += fmt.Sprintf("numSamples:       %d\n", r.conf.ObjectCount)
+	if r.conf.Duration > 0 {
+		output += fmt.Sprintf("Duration:         %s\n", r.conf.Duration)
+	}
+	if r.conf.Warmup > 0 {
+		output += fmt.Sprintf("Warmup:           %s\n", r.conf.Warmup)
+	}
+	if r.conf.WarmupOps > 0 {
+		output += fmt.Sprintf("Warmup:           %d ops\n", r.conf.WarmupOps)
+	}
+	if r.mix != nil {
+		output += fmt.Sprintf("Mix:              %s\n", r.mix)
+	}
+	if len(r.conf.Stages) > 0 {
+		labels := make([]string, len(r.conf.Stages))
+		for i, stage := range r.conf.Stages {
+			labels[i] = stage.Type
+			if stage.Name != "" {
+				labels[i] = stage.Name
+			}
+		}
+		output += fmt.Sprintf("Stages:           %s\n", strings.Join(labels, ", "))
+	}
+	if r.conf.Sweep != "" {
+		output += fmt.Sprintf("Sweep:            %s clients (%s)\n", r.conf.Sweep, r.conf.SweepOp)
+	}
+	if r.keyPattern != nil {
+		output += fmt.Sprintf("KeyPattern:       %s\n", r.keyPattern)
+	}
+	output += fmt.Sprintf("DataPattern:      %s\n", r.conf.pattern())
+	output += fmt.Sprintf("PayloadSigning:   %s\n", r.conf.PayloadSigning)
+	if r.conf.Checksum != "" {
+		output += fmt.Sprintf("Checksum:         %s\n", r.conf.Checksum)
+	}
+	if r.conf.SSEKMSKeyID != "" {
+		output += fmt.Sprintf("Encryption:       %s (key %s)\n", r.conf.SSE, r.conf.SSEKMSKeyID)
+	} else if r.conf.SSE != "" {
+		output += fmt.Sprintf("Encryption:       %s\n", r.conf.SSE)
+	}
+	if r.conf.Verify {
+		output += fmt.Sprintf("Verify:           seed %d\n", r.conf.Seed)
+	}
+	if r.conf.RangeSize > 0 {
+		output += fmt.Sprintf("RangeSize:        %d bytes (%s offsets)\n", r.conf.RangeSize, r.conf.RangeOffsets)
+	}
+	if r.conf.Rate > 0 {
+		output += fmt.Sprintf("Rate:             %g ops/s (%s arrivals)\n", r.conf.Rate, r.conf.Arrivals)
+	}
+	output += fmt.Sprintf("Precision:        %d\n", r.conf.Precision)
+	output += fmt.Sprintf("Interval:         %s\n", r.conf.Interval)
+	if r.conf.MetricsAddr != "" {
+		output += fmt.Sprintf("MetricsAddr:      %s\n", r.conf.MetricsAddr)
+	}
+	output += fmt.Sprintf("Output:           %s\n", r.conf.Output)
 	output += fmt.Sprintf("Verbose:          %t\n", r.conf.Verbose)
 	return output
 }
@@ -329,65 +1005,121 @@ func (r report) String() string {
 	report += fmt.Sprintf("Total Throughput:  %0.2f MB/s\n", (float64(r.bytesTransmitted)/(1024*1024))/r.totalDuration.Seconds())
 	report += fmt.Sprintf("Total Duration:    %0.3f s\n", r.totalDuration.Seconds())
 	report += fmt.Sprintf("Number of Errors:  %d\n", r.numErrors)
-	if len(r.opDurations) > 0 {
+	if r.count() > 0 {
 		report += fmt.Sprintln("------------------------------------")
-		report += fmt.Sprintf("%s times Max:       %0.3f s\n", r.Operation, r.percentile(100))
-		report += fmt.Sprintf("%s times 99th %%ile: %0.3f s\n", r.Operation, r.percentile(99))
-		report += fmt.Sprintf("%s times 90th %%ile: %0.3f s\n", r.Operation, r.percentile(90))
-		report += fmt.Sprintf("%s times 75th %%ile: %0.3f s\n", r.Operation, r.percentile(75))
-		report += fmt.Sprintf("%s times 50th %%ile: %0.3f s\n", r.Operation, r.percentile(50))
-		report += fmt.Sprintf("%s times 25th %%ile: %0.3f s\n", r.Operation, r.percentile(25))
-		report += fmt.Sprintf("%s times Min:       %0.3f s\n", r.Operation, r.percentile(0))
+		for _, p := range percentiles {
+			report += fmt.Sprintf("%s times %-10s %0.3f s\n", r.Operation, percentileLabel(p)+":", r.latency.percentile(p))
+		}
+	}
+	if r.corrected != nil && r.corrected.count() > 0 {
+		report += fmt.Sprintln("------------------------------------")
+		report += fmt.Sprintln("Corrected for coordinated omission:")
+		for _, p := range percentiles {
+			report += fmt.Sprintf("%s times %-10s %0.3f s\n", r.Operation, percentileLabel(p)+":", r.corrected.percentile(p))
+		}
+	}
+	if r.ttfb != nil && r.ttfb.count() > 0 {
+		report += fmt.Sprintln("------------------------------------")
+		for _, p := range percentiles {
+			report += fmt.Sprintf("%s TTFB  %-10s %0.3f s\n", r.Operation, percentileLabel(p)+":", r.ttfb.percentile(p))
+		}
+	}
+	if len(r.errors) > 0 {
+		report += fmt.Sprintln("------------------------------------")
+		for _, class := range r.errors.sorted() {
+			report += fmt.Sprintf("Errors %s: %d (e.g. %s)\n", class.key(), class.Count, class.Example)
+		}
+	}
+	if r.warmup != nil {
+		report += fmt.Sprintln("------------------------------------")
+		report += fmt.Sprintf("Warm-up of %0.3f s (excluded): %s\n", r.warmupDuration.Seconds(), r.warmup)
+	}
+	if len(r.mix) > 0 {
+		report += fmt.Sprintln("------------------------------------")
+		for _, name := range sortedNames(r.mix) {
+			report += fmt.Sprintf("Operation %s: %s\n", name, r.mix[name])
+		}
+	}
+	if len(r.sizes) > 0 {
+		report += fmt.Sprintln("------------------------------------")
+		for _, bucket := range r.sizes.sorted() {
+			report += fmt.Sprintf("Size %s: %s\n", r.sizes.label(bucket), r.sizes[bucket])
+		}
+	}
+	if len(r.endpoints) > 1 {
+		report += fmt.Sprintln("------------------------------------")
+		for _, name := range sortedNames(r.endpoints) {
+			report += fmt.Sprintf("Endpoint %s: %s\n", name, r.endpoints[name])
+		}
 	}
 	return report
 }
 
-func (r report) percentile(i int) float64 {
-	if i >= 100 {
-		i = len(r.opDurations) - 1
-	} else if i > 0 && i < 100 {
-		i = int(float64(i) / 100 * float64(len(r.opDurations)))
+func (r report) result() OperationResult {
+	result := OperationResult{
+		Operation:  r.Operation,
+		StartTime:  r.startTime,
+		EndTime:    r.startTime.Add(r.totalDuration),
+		Duration:   r.totalDuration.Seconds(),
+		Count:      r.count(),
+		Bytes:      r.bytesTransmitted,
+		Errors:     r.numErrors,
+		Throughput: (float64(r.bytesTransmitted) / (1024 * 1024)) / r.totalDuration.Seconds(),
+		Intervals:  r.intervals,
 	}
-	return r.opDurations[i]
-}
-
-func (r *RepeatReader) Read(b []byte) (int, error) {
-	n, err := r.reader.Read(b)
-	if err != nil && err != io.EOF {
-		return n, err
+	if result.Count > 0 {
+		for _, p := range percentiles {
+			result.Percentiles = append(result.Percentiles, Percentile{p, r.latency.percentile(p)})
+		}
+		result.Histogram = r.latency.buckets(histogramBuckets)
 	}
-	if err != nil && n != 0 {
-		return n, nil
+	if r.ttfb != nil && r.ttfb.count() > 0 {
+		for _, p := range percentiles {
+			result.TTFBPercentiles = append(result.TTFBPercentiles, Percentile{p, r.ttfb.percentile(p)})
+		}
+		result.TTFBHistogram = r.ttfb.buckets(histogramBuckets)
 	}
-	if err != nil {
-		r.currentRepeat += 1
-		if r.currentRepeat == r.numRepeat {
-			return n, err
+	if r.corrected != nil && r.corrected.count() > 0 {
+		for _, p := range percentiles {
+			result.CorrectedPercentiles = append(result.CorrectedPercentiles, Percentile{p, r.corrected.percentile(p)})
 		}
-		r.reader.Seek(0, io.SeekStart)
-		return r.Read(b)
+		result.CorrectedHistogram = r.corrected.buckets(histogramBuckets)
+	}
+	if len(r.endpoints) > 1 {
+		result.Endpoints = breakdowns(r.endpoints, r.totalDuration)
+	}
+	if len(r.mix) > 0 {
+		result.Mix = breakdowns(r.mix, r.totalDuration)
+	}
+	if r.warmup != nil && r.warmupDuration > 0 {
+		warmup := r.warmup.breakdown("Warm-up", r.warmupDuration)
+		result.Warmup = &warmup
 	}
-	return n, err
+	if len(r.sizes) > 0 {
+		result.Sizes = r.sizes.breakdowns(r.totalDuration)
+	}
+	if len(r.errors) > 0 {
+		result.ErrorClasses = r.errors.sorted()
+	}
+	return result
 }
 
-func (r *RepeatReader) Seek(offset int64, whence int) (int64, error) {
-	if whence == io.SeekCurrent {
-		currentOffset, err := r.reader.Seek(0, io.SeekCurrent)
-		if err != nil {
-			return currentOffset * int64(r.currentRepeat), err
-		}
-		offset = currentOffset + offset
-		whence = io.SeekStart
+// count returns the number of successful operations
+func (r report) count() int {
+	if r.latency == nil {
+		return 0
 	}
-	div, rem := offset/int64(r.numBytes), offset%int64(r.numBytes)
-	switch whence {
-	case io.SeekStart:
-		r.currentRepeat = uint(div)
-	case io.SeekEnd:
-		r.currentRepeat = r.numRepeat - uint(div) - 1
+	return int(r.latency.count())
+}
+
+func percentileLabel(p float64) string {
+	switch p {
+	case 100:
+		return "Max"
+	case 0:
+		return "Min"
 	}
-	currentOffset, err := r.reader.Seek(rem, whence)
-	return int64(r.numBytes)*int64(r.currentRepeat) + currentOffset, err
+	return fmt.Sprintf("%gth %%ile", p)
 }
 
 func (w *DiscardAt) WriteAt(p []byte, off int64) (n int, err error) {
@@ -0,0 +1,126 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+)
+
+// testConfig returns the configuration of the SDK for a test server
+func testConfig() *aws.Config {
+	return &aws.Config{
+		Credentials:      credentials.NewStaticCredentials("access", "secret", ""),
+		Region:           aws.String("us-east-1"),
+		S3ForcePathStyle: aws.Bool(true),
+		MaxRetries:       aws.Int(0),
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var body struct {
+			Objects []struct{ Key string } `xml:"Object"`
+		}
+		if req.Method != http.MethodPost || req.URL.Path != "/bucket" || xml.NewDecoder(req.Body).Decode(&body) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, object := range body.Objects {
+			deleted = append(deleted, object.Key)
+		}
+		w.Write([]byte("<DeleteResult></DeleteResult>"))
+	}))
+	defer server.Close()
+	r := &Runner{
+		conf:      &Config{Bucket: "bucket", ObjectNamePrefix: "object", ObjectCount: 4, Cleanup: true},
+		endpoints: []string{server.URL},
+		out:       ioutil.Discard,
+	}
+	r.cleanup(testConfig())
+	want := []string{"object0", "object1", "object2", "object3"}
+	if len(deleted) != len(want) {
+		t.Fatalf("cleanup() deleted %v from the configured endpoint, want %v", deleted, want)
+	}
+	for i := range want {
+		if deleted[i] != want[i] {
+			t.Errorf("cleanup() deleted %s, want %s", deleted[i], want[i])
+		}
+	}
+}
+
+// warmupRecorder records the keys of the samples flagged as warm-up
+type warmupRecorder map[string]bool
+
+func (w warmupRecorder) write(resp response, warmup bool) {
+	w[resp.key] = warmup
+}
+
+func (w warmupRecorder) close() error {
+	return nil
+}
+
+func TestRunWarmupOps(t *testing.T) {
+	conf := &Config{Endpoint: "http://localhost", ObjectNamePrefix: "object", Clients: 1, ObjectCount: 6, WarmupOps: 2, ShowWarmup: true}
+	if err := conf.validate(); err != nil {
+		t.Fatal(err)
+	}
+	samples := warmupRecorder{}
+	r := &Runner{
+		conf:      conf,
+		samples:   samples,
+		requests:  make(chan request),
+		responses: make(chan response),
+		keys:      conf.ObjectCount,
+		out:       ioutil.Discard,
+	}
+	go func() {
+		// The warm-up requests complete last, after the measured ones
+		start := time.Now()
+		var requests []request
+		for i := uint(0); i < conf.ObjectCount; i++ {
+			requests = append(requests, <-r.requests)
+		}
+		for _, req := range append(requests[conf.WarmupOps:], requests[:conf.WarmupOps]...) {
+			r.responses <- response{op: req.op, key: req.key, start: start, duration: time.Millisecond, warmup: req.warmup}
+		}
+	}()
+	report := r.run(readOp)
+	if got := report.latency.count(); got != 4 {
+		t.Errorf("run() measured %d operations, want 4", got)
+	}
+	if got := report.warmup.latency.count(); got != 2 {
+		t.Errorf("run() left %d operations out as the warm-up, want 2", got)
+	}
+	for key, warmup := range samples {
+		if want := key == "object0" || key == "object1"; warmup != want {
+			t.Errorf("run() flagged the sample of %s as warm-up %t, want %t", key, warmup, want)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"text/tabwriter"
+)
+
+// LoadResult reads a result saved with the json output format
+func LoadResult(path string) (*Result, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var result Result
+	if err := json.NewDecoder(file).Decode(&result); err != nil {
+		return nil, fmt.Errorf("Unable to parse %s: %v", path, err)
+	}
+	return &result, nil
+}
+
+// metric is a single comparable value of an OperationResult
+type metric struct {
+	name           string
+	unit           string
+	higherIsBetter bool
+	value          func(OperationResult) (float64, bool)
+}
+
+func comparedMetrics(baseline OperationResult) []metric {
+	metrics := []metric{
+		{"Throughput", "MB/s", true, func(o OperationResult) (float64, bool) {
+			return o.Throughput, true
+		}},
+		{"Operations", "ops/s", true, func(o OperationResult) (float64, bool) {
+			return float64(o.Count) / o.Duration, o.Duration > 0
+		}},
+		{"Errors", "", false, func(o OperationResult) (float64, bool) {
+			return float64(o.Errors), true
+		}},
+	}
+	for _, p := range baseline.Percentiles {
+		metrics = append(metrics, percentileMetric("", p.Percentile, func(o OperationResult) []Percentile {
+			return o.Percentiles
+		}))
+	}
+	for _, p := range baseline.CorrectedPercentiles {
+		metrics = append(metrics, percentileMetric("Corrected ", p.Percentile, func(o OperationResult) []Percentile {
+			return o.CorrectedPercentiles
+		}))
+	}
+	for _, p := range baseline.TTFBPercentiles {
+		metrics = append(metrics, percentileMetric("TTFB ", p.Percentile, func(o OperationResult) []Percentile {
+			return o.TTFBPercentiles
+		}))
+	}
+	return metrics
+}
+
+func percentileMetric(prefix string, percentile float64, list func(OperationResult) []Percentile) metric {
+	return metric{prefix + percentileLabel(percentile), "s", false, func(o OperationResult) (float64, bool) {
+		for _, p := range list(o) {
+			if p.Percentile == percentile {
+				return p.Seconds, true
+			}
+		}
+		return 0, false
+	}}
+}
+
+// Compare writes a side-by-side table of every operation found in the
+// results, using the first one as the baseline. Relative changes larger than
+// threshold percent are flagged. It returns the number of regressions.
+func Compare(w io.Writer, names []string, results []*Result, threshold float64) int {
+	regressions := 0
+	for _, baseline := range results[0].Operations {
+		fmt.Fprintf(w, "Comparison for %s Operation(s)\n", baseline.Operation)
+		table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(table, "Metric\t%s", names[0])
+		for _, name := range names[1:] {
+			fmt.Fprintf(table, "\t%s\tDelta\tDelta %%\t", name)
+		}
+		fmt.Fprintln(table)
+		for _, m := range comparedMetrics(baseline) {
+			base, ok := m.value(baseline)
+			if !ok {
+				continue
+			}
+			fmt.Fprintf(table, "%s\t%s", m.name, formatMetric(base, m.unit))
+			for _, result := range results[1:] {
+				other, found := findOperation(result, baseline.Operation)
+				value, ok := m.value(other)
+				if !found || !ok {
+					fmt.Fprint(table, "\t-\t\t\t")
+					continue
+				}
+				delta := value - base
+				relative := math.Inf(1)
+				if base != 0 {
+					relative = delta / base * 100
+				} else if delta == 0 {
+					relative = 0
+				}
+				flag := ""
+				if math.Abs(relative) > threshold {
+					if (delta > 0) == m.higherIsBetter {
+						flag = "improvement"
+					} else {
+						flag = "REGRESSION"
+						regressions++
+					}
+				}
+				fmt.Fprintf(table, "\t%s\t%+0.3f\t%s\t%s", formatMetric(value, m.unit), delta, formatRelative(relative), flag)
+			}
+			fmt.Fprintln(table)
+		}
+		table.Flush()
+		fmt.Fprintln(w)
+	}
+	return regressions
+}
+
+func findOperation(result *Result, operation string) (OperationResult, bool) {
+	for _, o := range result.Operations {
+		if o.Operation == operation {
+			return o, true
+		}
+	}
+	return OperationResult{}, false
+}
+
+func formatMetric(value float64, unit string) string {
+	if unit == "" {
+		return fmt.Sprintf("%g", value)
+	}
+	return fmt.Sprintf("%0.3f %s", value, unit)
+}
+
+func formatRelative(relative float64) string {
+	if math.IsInf(relative, 0) {
+		return "n/a"
+	}
+	return fmt.Sprintf("%+0.1f%%", relative)
+}
@@ -0,0 +1,71 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func compareResult(throughput float64, errors int, p99 float64) *Result {
+	return &Result{Operations: []OperationResult{{
+		Operation:   "Write",
+		Duration:    10,
+		Count:       100,
+		Errors:      errors,
+		Throughput:  throughput,
+		Percentiles: []Percentile{{Percentile: 99, Seconds: p99}},
+	}}}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name        string
+		other       *Result
+		threshold   float64
+		regressions int
+		contains    []string
+	}{
+		{"unchanged", compareResult(100, 0, 0.5), 5, 0, []string{"+0.000", "+0.0%"}},
+		{"slower within threshold", compareResult(96, 0, 0.51), 5, 0, []string{"-4.000", "-4.0%", "+2.0%"}},
+		{"slower", compareResult(80, 0, 0.5), 5, 1, []string{"-20.000", "-20.0%", "REGRESSION"}},
+		{"faster", compareResult(120, 0, 0.25), 5, 0, []string{"+20.0%", "-50.0%", "improvement"}},
+		{"higher latency", compareResult(100, 0, 1), 5, 1, []string{"+0.500", "+100.0%", "REGRESSION"}},
+		{"new errors", compareResult(100, 3, 0.5), 5, 1, []string{"+3.000", "n/a", "REGRESSION"}},
+		{"several regressions", compareResult(50, 1, 2), 5, 3, nil},
+		{"missing operation", &Result{}, 5, 0, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			regressions := Compare(&out, []string{"base", "other"}, []*Result{compareResult(100, 0, 0.5), test.other}, test.threshold)
+			if regressions != test.regressions {
+				t.Errorf("Compare() = %d regressions, want %d\n%s", regressions, test.regressions, out.String())
+			}
+			for _, s := range test.contains {
+				if !strings.Contains(out.String(), s) {
+					t.Errorf("Compare() output is missing %q\n%s", s, out.String())
+				}
+			}
+		})
+	}
+}
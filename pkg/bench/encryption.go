@@ -0,0 +1,101 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+const (
+	sseS3  = "sse-s3"
+	sseKMS = "sse-kms"
+	sseC   = "sse-c"
+)
+
+func (conf *Config) validateEncryption() error {
+	switch conf.SSE {
+	case "", sseS3, sseKMS:
+	case sseC:
+		if _, err := conf.customerKey(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("sse(%s) needs to be one of %q, %q or %q", conf.SSE, sseS3, sseKMS, sseC)
+	}
+	if conf.SSEKMSKeyID != "" && conf.SSE != sseKMS {
+		return fmt.Errorf("sseKmsKeyId can only be used with %q", sseKMS)
+	}
+	if conf.SSECustomerKey != "" && conf.SSE != sseC {
+		return fmt.Errorf("sseCustomerKey can only be used with %q", sseC)
+	}
+	return nil
+}
+
+// customerKey decodes the SSE-C key, which the SDK takes as raw bytes
+func (conf *Config) customerKey() (string, error) {
+	key, err := base64.StdEncoding.DecodeString(conf.SSECustomerKey)
+	if err != nil || len(key) != 32 {
+		return "", fmt.Errorf("sseCustomerKey needs to be a base64 encoded 256-bit key")
+	}
+	return string(key), nil
+}
+
+// encrypt sets the server-side encryption parameters of the input. Objects
+// encrypted with customer keys also need the key to be read or copied.
+func (r *Runner) encrypt(input interface{}) {
+	if r.conf.SSE == "" {
+		return
+	}
+	var algorithm, kmsKeyID, customerAlgorithm, customerKey *string
+	switch r.conf.SSE {
+	case sseS3:
+		algorithm = aws.String(s3.ServerSideEncryptionAes256)
+	case sseKMS:
+		algorithm = aws.String(s3.ServerSideEncryptionAwsKms)
+		if r.conf.SSEKMSKeyID != "" {
+			kmsKeyID = aws.String(r.conf.SSEKMSKeyID)
+		}
+	case sseC:
+		key, _ := r.conf.customerKey()
+		customerAlgorithm, customerKey = aws.String(s3.ServerSideEncryptionAes256), aws.String(key)
+	}
+	switch input := input.(type) {
+	case *s3.PutObjectInput:
+		input.ServerSideEncryption, input.SSEKMSKeyId = algorithm, kmsKeyID
+		input.SSECustomerAlgorithm, input.SSECustomerKey = customerAlgorithm, customerKey
+	case *s3manager.UploadInput:
+		input.ServerSideEncryption, input.SSEKMSKeyId = algorithm, kmsKeyID
+		input.SSECustomerAlgorithm, input.SSECustomerKey = customerAlgorithm, customerKey
+	case *s3.CopyObjectInput:
+		input.ServerSideEncryption, input.SSEKMSKeyId = algorithm, kmsKeyID
+		input.SSECustomerAlgorithm, input.SSECustomerKey = customerAlgorithm, customerKey
+		input.CopySourceSSECustomerAlgorithm, input.CopySourceSSECustomerKey = customerAlgorithm, customerKey
+	case *s3.GetObjectInput:
+		input.SSECustomerAlgorithm, input.SSECustomerKey = customerAlgorithm, customerKey
+	case *s3.HeadObjectInput:
+		input.SSECustomerAlgorithm, input.SSECustomerKey = customerAlgorithm, customerKey
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package bench
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// testCustomerKey is a base64 encoded 256-bit key
+const testCustomerKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+
+func TestValidateEncryption(t *testing.T) {
+	tests := []struct {
+		name  string
+		conf  Config
+		valid bool
+	}{
+		{"none", Config{}, true},
+		{"sse-s3", Config{SSE: sseS3}, true},
+		{"sse-kms", Config{SSE: sseKMS}, true},
+		{"sse-kms with a key", Config{SSE: sseKMS, SSEKMSKeyID: "key"}, true},
+		{"sse-c", Config{SSE: sseC, SSECustomerKey: testCustomerKey}, true},
+		{"unknown", Config{SSE: "aes"}, false},
+		{"sse-c without a key", Config{SSE: sseC}, false},
+		{"sse-c with a short key", Config{SSE: sseC, SSECustomerKey: "c2hvcnQ="}, false},
+		{"sse-c with a key that isn't base64", Config{SSE: sseC, SSECustomerKey: "not base64!"}, false},
+		{"stray kms key", Config{SSE: sseS3, SSEKMSKeyID: "key"}, false},
+		{"stray customer key", Config{SSECustomerKey: testCustomerKey}, false},
+		{"customer key with sse-kms", Config{SSE: sseKMS, SSECustomerKey: testCustomerKey}, false},
+	}
+	for _, test := range tests {
+		err := test.conf.validateEncryption()
+		if (err == nil) != test.valid {
+			t.Errorf("%s: validateEncryption() returned %v", test.name, err)
+		}
+	}
+}
+
+func TestEncrypt(t *testing.T) {
+	key := "0123456789abcdef0123456789abcdef"
+	tests := []struct {
+		name string
+		conf Config
+		// algorithm, kmsKeyID and customerKey are expected on every input
+		// that takes them, empty meaning unset
+		algorithm, kmsKeyID, customerKey string
+	}{
+		{"none", Config{}, "", "", ""},
+		{"sse-s3", Config{SSE: sseS3}, s3.ServerSideEncryptionAes256, "", ""},
+		{"sse-kms", Config{SSE: sseKMS}, s3.ServerSideEncryptionAwsKms, "", ""},
+		{"sse-kms with a key", Config{SSE: sseKMS, SSEKMSKeyID: "key"}, s3.ServerSideEncryptionAwsKms, "key", ""},
+		{"sse-c", Config{SSE: sseC, SSECustomerKey: testCustomerKey}, "", "", key},
+	}
+	for _, test := range tests {
+		r := &Runner{conf: &test.conf}
+		put, upload, copyInput := &s3.PutObjectInput{}, &s3manager.UploadInput{}, &s3.CopyObjectInput{}
+		get, head := &s3.GetObjectInput{}, &s3.HeadObjectInput{}
+		for _, input := range []interface{}{put, upload, copyInput, get, head, &s3.DeleteObjectInput{}} {
+			r.encrypt(input)
+		}
+		customerAlgorithm := ""
+		if test.customerKey != "" {
+			customerAlgorithm = s3.ServerSideEncryptionAes256
+		}
+		inputs := []struct {
+			input string
+			// writes is set for the inputs creating objects
+			writes                                              bool
+			algorithm, kmsKeyID, customerAlgorithm, customerKey *string
+		}{
+			{"put", true, put.ServerSideEncryption, put.SSEKMSKeyId, put.SSECustomerAlgorithm, put.SSECustomerKey},
+			{"upload", true, upload.ServerSideEncryption, upload.SSEKMSKeyId, upload.SSECustomerAlgorithm, upload.SSECustomerKey},
+			{"copy", true, copyInput.ServerSideEncryption, copyInput.SSEKMSKeyId, copyInput.SSECustomerAlgorithm, copyInput.SSECustomerKey},
+			{"copy source", false, nil, nil, copyInput.CopySourceSSECustomerAlgorithm, copyInput.CopySourceSSECustomerKey},
+			{"get", false, nil, nil, get.SSECustomerAlgorithm, get.SSECustomerKey},
+			{"head", false, nil, nil, head.SSECustomerAlgorithm, head.SSECustomerKey},
+		}
+		for _, w := range inputs {
+			if w.writes {
+				if got := aws.StringValue(w.algorithm); got != test.algorithm {
+					t.Errorf("%s: encrypt() set the algorithm of %s to %q, want %q", test.name, w.input, got, test.algorithm)
+				}
+				if got := aws.StringValue(w.kmsKeyID); got != test.kmsKeyID {
+					t.Errorf("%s: encrypt() set the KMS key of %s to %q, want %q", test.name, w.input, got, test.kmsKeyID)
+				}
+			}
+			if got := aws.StringValue(w.customerAlgorithm); got != customerAlgorithm {
+				t.Errorf("%s: encrypt() set the customer algorithm of %s to %q, want %q", test.name, w.input, got, customerAlgorithm)
+			}
+			if got := aws.StringValue(w.customerKey); got != test.customerKey {
+				t.Errorf("%s: encrypt() set the customer key of %s to %q, want %q", test.name, w.input, got, test.customerKey)
+			}
+		}
+	}
+}
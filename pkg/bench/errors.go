@@ -0,0 +1,130 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"syscall"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// ErrorClass groups the errors of an operation that share the same cause
+type ErrorClass struct {
+	Class      string `json:"class"`
+	HTTPStatus string `json:"httpStatus,omitempty"`
+	Count      int    `json:"count"`
+	Example    string `json:"example"`
+}
+
+func (c ErrorClass) key() string {
+	if c.HTTPStatus == "" {
+		return c.Class
+	}
+	return fmt.Sprintf("%s (HTTP %s)", c.Class, c.HTTPStatus)
+}
+
+// lengthError reports an object that wasn't of the expected length
+type lengthError struct {
+	expected int64
+	actual   int64
+}
+
+func (e lengthError) Error() string {
+	return fmt.Sprintf("Expected object length %d, actual %d", e.expected, e.actual)
+}
+
+// classifyError returns the class of err, without its count and example
+func classifyError(err error) ErrorClass {
+	var length lengthError
+	if errors.As(err, &length) {
+		return ErrorClass{Class: "LengthMismatch"}
+	}
+	var integrity integrityError
+	if errors.As(err, &integrity) {
+		return ErrorClass{Class: "IntegrityError"}
+	}
+	if failure, ok := err.(awserr.RequestFailure); ok && failure.StatusCode() > 0 {
+		return ErrorClass{
+			Class:      failure.Code(),
+			HTTPStatus: fmt.Sprintf("%dxx", failure.StatusCode()/100),
+		}
+	}
+	// The SDK wraps transport errors, e.g. in a RequestError
+	cause := err
+	if awsErr, ok := err.(awserr.Error); ok {
+		if awsErr.OrigErr() == nil {
+			return ErrorClass{Class: awsErr.Code()}
+		}
+		cause = awsErr.OrigErr()
+	}
+	var netErr net.Error
+	switch {
+	case errors.As(cause, &netErr) && netErr.Timeout():
+		return ErrorClass{Class: "NetworkTimeout"}
+	case errors.Is(cause, syscall.ECONNRESET):
+		return ErrorClass{Class: "ConnectionReset"}
+	case errors.Is(cause, syscall.ECONNREFUSED):
+		return ErrorClass{Class: "ConnectionRefused"}
+	case errors.Is(cause, io.EOF), errors.Is(cause, io.ErrUnexpectedEOF):
+		return ErrorClass{Class: "UnexpectedEOF"}
+	case errors.As(cause, &netErr):
+		return ErrorClass{Class: "NetworkError"}
+	}
+	if awsErr, ok := err.(awserr.Error); ok {
+		return ErrorClass{Class: awsErr.Code()}
+	}
+	return ErrorClass{Class: "Other"}
+}
+
+// errorClasses counts the errors of a report by class, keeping the first
+// message seen for each one
+type errorClasses map[string]*ErrorClass
+
+func (e errorClasses) add(err error) {
+	class := classifyError(err)
+	if existing, ok := e[class.key()]; ok {
+		existing.Count++
+		return
+	}
+	class.Count = 1
+	class.Example = err.Error()
+	e[class.key()] = &class
+}
+
+// sorted returns the classes from the most to the least frequent
+func (e errorClasses) sorted() []ErrorClass {
+	classes := make([]ErrorClass, 0, len(e))
+	for _, class := range e {
+		classes = append(classes, *class)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		if classes[i].Count != classes[j].Count {
+			return classes[i].Count > classes[j].Count
+		}
+		return classes[i].key() < classes[j].key()
+	})
+	return classes
+}
@@ -0,0 +1,85 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		class string
+		http  string
+	}{
+		{"length", lengthError{expected: 10, actual: 5}, "LengthMismatch", ""},
+		{"wrapped length", fmt.Errorf("read: %w", lengthError{expected: 10, actual: 5}), "LengthMismatch", ""},
+		{"integrity", integrityError{key: "key", offset: 42}, "IntegrityError", ""},
+		{"not found", awserr.NewRequestFailure(awserr.New("NoSuchKey", "missing", nil), 404, "id"), "NoSuchKey", "4xx"},
+		{"slow down", awserr.NewRequestFailure(awserr.New("SlowDown", "slow down", nil), 503, "id"), "SlowDown", "5xx"},
+		{"sdk error", awserr.New("SerializationError", "failed", nil), "SerializationError", ""},
+		{"timeout", awserr.New("RequestError", "send request failed", &net.OpError{Op: "read", Err: timeoutError{}}), "NetworkTimeout", ""},
+		{"reset", awserr.New("RequestError", "send request failed", &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), "ConnectionReset", ""},
+		{"refused", awserr.New("RequestError", "send request failed", &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), "ConnectionRefused", ""},
+		{"eof", awserr.New("RequestError", "send request failed", io.ErrUnexpectedEOF), "UnexpectedEOF", ""},
+		{"network", awserr.New("RequestError", "send request failed", &net.DNSError{Err: "no such host", Name: "s3"}), "NetworkError", ""},
+		{"unknown cause", awserr.New("RequestError", "send request failed", errors.New("boom")), "RequestError", ""},
+		{"other", errors.New("boom"), "Other", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			class := classifyError(test.err)
+			if class.Class != test.class || class.HTTPStatus != test.http {
+				t.Errorf("classifyError(%v) = %s (HTTP %q), want %s (HTTP %q)", test.err, class.Class, class.HTTPStatus, test.class, test.http)
+			}
+		})
+	}
+}
+
+func TestErrorClassesSorted(t *testing.T) {
+	classes := errorClasses{}
+	classes.add(errors.New("first"))
+	for i := 0; i < 2; i++ {
+		classes.add(lengthError{expected: 10, actual: int64(i)})
+	}
+	sorted := classes.sorted()
+	if len(sorted) != 2 || sorted[0].Class != "LengthMismatch" || sorted[0].Count != 2 || sorted[1].Class != "Other" {
+		t.Fatalf("sorted() = %+v", sorted)
+	}
+	if sorted[0].Example != "Expected object length 10, actual 0" {
+		t.Errorf("sorted()[0].Example = %q, want the first error seen", sorted[0].Example)
+	}
+}
@@ -0,0 +1,314 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+const (
+	chartWidth   = 720
+	chartHeight  = 280
+	chartLeft    = 70
+	chartRight   = 20
+	chartTop     = 30
+	chartBottom  = 40
+	chartTicks   = 5
+	chartColor   = "#1f77b4"
+	chartCaption = "#444"
+)
+
+// chart is a minimal SVG line or bar chart, so that the report doesn't
+// depend on any external script
+type chart struct {
+	title  string
+	xLabel string
+	yLabel string
+	logX   bool
+	// For bar charts, lows holds the lower edge of every bar and xs the
+	// upper one
+	lows []float64
+	xs   []float64
+	ys   []float64
+}
+
+func (c chart) svg() template.HTML {
+	if len(c.xs) == 0 {
+		return template.HTML(fmt.Sprintf("<p>%s: no data</p>", template.HTMLEscapeString(c.title)))
+	}
+	xmin, xmax := c.xs[0], c.xs[len(c.xs)-1]
+	if len(c.lows) > 0 {
+		xmin = c.lows[0]
+	} else if !c.logX {
+		xmin = math.Min(xmin, 0)
+	}
+	ymax := 0.0
+	for _, y := range c.ys {
+		ymax = math.Max(ymax, y)
+	}
+	if ymax == 0 {
+		ymax = 1
+	}
+	if xmax <= xmin {
+		xmax = xmin + 1
+	}
+	plotWidth := float64(chartWidth - chartLeft - chartRight)
+	plotHeight := float64(chartHeight - chartTop - chartBottom)
+	scaleX := func(x float64) float64 {
+		if c.logX {
+			return chartLeft + (math.Log10(x)-math.Log10(xmin))/(math.Log10(xmax)-math.Log10(xmin))*plotWidth
+		}
+		return chartLeft + (x-xmin)/(xmax-xmin)*plotWidth
+	}
+	scaleY := func(y float64) float64 {
+		return chartTop + plotHeight - y/ymax*plotHeight
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d">`, chartWidth, chartHeight)
+	fmt.Fprintf(&b, `<text x="%d" y="18" font-weight="bold">%s</text>`, chartLeft, template.HTMLEscapeString(c.title))
+	for i := 0; i <= chartTicks; i++ {
+		y := ymax * float64(i) / chartTicks
+		fmt.Fprintf(&b, `<line x1="%d" x2="%d" y1="%0.1f" y2="%0.1f" stroke="#ddd"/>`, chartLeft, chartWidth-chartRight, scaleY(y), scaleY(y))
+		fmt.Fprintf(&b, `<text x="%d" y="%0.1f" text-anchor="end" fill="%s">%s</text>`, chartLeft-6, scaleY(y)+4, chartCaption, formatTick(y))
+	}
+	for _, x := range c.xTicks(xmin, xmax) {
+		fmt.Fprintf(&b, `<text x="%0.1f" y="%d" text-anchor="middle" fill="%s">%s</text>`, scaleX(x), chartHeight-chartBottom+16, chartCaption, formatTick(x))
+	}
+	fmt.Fprintf(&b, `<text x="%0.1f" y="%d" text-anchor="middle" fill="%s">%s</text>`, chartLeft+plotWidth/2, chartHeight-6, chartCaption, template.HTMLEscapeString(c.xLabel))
+	fmt.Fprintf(&b, `<text x="14" y="%0.1f" text-anchor="middle" fill="%s" transform="rotate(-90 14 %0.1f)">%s</text>`, chartTop+plotHeight/2, chartCaption, chartTop+plotHeight/2, template.HTMLEscapeString(c.yLabel))
+	if len(c.lows) > 0 {
+		for i := range c.xs {
+			x0, x1 := scaleX(c.lows[i]), scaleX(c.xs[i])
+			fmt.Fprintf(&b, `<rect x="%0.1f" y="%0.1f" width="%0.1f" height="%0.1f" fill="%s"/>`, x0, scaleY(c.ys[i]), math.Max(x1-x0-1, 1), scaleY(0)-scaleY(c.ys[i]), chartColor)
+		}
+	} else {
+		points := make([]string, len(c.xs))
+		for i := range c.xs {
+			points[i] = fmt.Sprintf("%0.1f,%0.1f", scaleX(c.xs[i]), scaleY(c.ys[i]))
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`, chartColor, strings.Join(points, " "))
+	}
+	fmt.Fprintf(&b, `<line x1="%d" x2="%d" y1="%0.1f" y2="%0.1f" stroke="%s"/>`, chartLeft, chartWidth-chartRight, scaleY(0), scaleY(0), chartCaption)
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+func (c chart) xTicks(xmin, xmax float64) []float64 {
+	var ticks []float64
+	if c.logX {
+		for p := math.Floor(math.Log10(xmin)); p <= math.Ceil(math.Log10(xmax)); p++ {
+			if x := math.Pow(10, p); x >= xmin && x <= xmax {
+				ticks = append(ticks, x)
+			}
+		}
+		return append([]float64{xmin}, append(ticks, xmax)...)
+	}
+	for i := 0; i <= chartTicks; i++ {
+		ticks = append(ticks, xmin+(xmax-xmin)*float64(i)/chartTicks)
+	}
+	return ticks
+}
+
+func formatTick(v float64) string {
+	return fmt.Sprintf("%.3g", v)
+}
+
+func throughputChart(o OperationResult) chart {
+	c := chart{title: o.Operation + " throughput over time", xLabel: "time (s)", yLabel: "MB/s"}
+	if o.Bytes == 0 {
+		c.yLabel = "ops/s"
+	}
+	elapsed := 0.0
+	for _, i := range o.Intervals {
+		elapsed += i.Duration
+		c.xs = append(c.xs, elapsed)
+		if o.Bytes == 0 {
+			c.ys = append(c.ys, i.OpsPerSec)
+		} else {
+			c.ys = append(c.ys, i.Throughput)
+		}
+	}
+	return c
+}
+
+func latencyCDFChart(o OperationResult) chart {
+	c := chart{title: o.Operation + " latency CDF", xLabel: "operation time (s)", yLabel: "% of operations", logX: true}
+	total, cumulative := int64(0), int64(0)
+	for _, b := range o.Histogram {
+		total += b.Count
+	}
+	for _, b := range o.Histogram {
+		cumulative += b.Count
+		c.xs = append(c.xs, b.UpperBound)
+		c.ys = append(c.ys, float64(cumulative)/float64(total)*100)
+	}
+	return c
+}
+
+func ttfbCDFChart(o OperationResult) chart {
+	c := latencyCDFChart(OperationResult{Histogram: o.TTFBHistogram})
+	c.title = o.Operation + " time to first byte CDF"
+	c.xLabel = "time to first byte (s)"
+	return c
+}
+
+func correctedCDFChart(o OperationResult) chart {
+	c := latencyCDFChart(OperationResult{Histogram: o.CorrectedHistogram})
+	c.title = o.Operation + " latency CDF corrected for coordinated omission"
+	return c
+}
+
+func latencyHistogramChart(o OperationResult) chart {
+	c := chart{title: o.Operation + " latency histogram", xLabel: "operation time (s)", yLabel: "operations", logX: true}
+	for i, b := range o.Histogram {
+		low := b.UpperBound * 0.9
+		if i > 0 {
+			low = o.Histogram[i-1].UpperBound
+		} else if len(o.Histogram) > 1 {
+			low = b.UpperBound * b.UpperBound / o.Histogram[1].UpperBound
+		}
+		c.lows = append(c.lows, low)
+		c.xs = append(c.xs, b.UpperBound)
+		c.ys = append(c.ys, float64(b.Count))
+	}
+	return c
+}
+
+// htmlTable lists the breakdowns of an operation, e.g. per endpoint
+type htmlTable struct {
+	Title string
+	Rows  []Breakdown
+}
+
+type htmlOperation struct {
+	OperationResult
+	Tables []htmlTable
+	Charts []template.HTML
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"label":     percentileLabel,
+	"errorRate": errorRate,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>benchio report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+pre { background: #f4f4f4; padding: 1em; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+</style>
+</head>
+<body>
+<h1>benchio report</h1>
+<p>{{.Result.StartTime.Format "2006-01-02 15:04:05 MST"}} to {{.Result.EndTime.Format "2006-01-02 15:04:05 MST"}}</p>
+<h2>Test parameters</h2>
+<pre>{{.Result.Parameters}}</pre>
+<h2>Summary</h2>
+<table>
+<tr><th>Operation</th><th>Count</th><th>Errors</th><th>Duration (s)</th><th>Throughput (MB/s)</th>{{range .Percentiles}}<th>{{label .}} (s)</th>{{end}}</tr>
+{{range .Operations}}<tr><td>{{.Operation}}</td><td>{{.Count}}</td><td>{{.Errors}}</td><td>{{printf "%0.3f" .Duration}}</td><td>{{printf "%0.2f" .Throughput}}</td>{{range .Percentiles}}<td>{{printf "%0.3f" .Seconds}}</td>{{end}}</tr>
+{{end}}</table>
+{{range .Operations}}<h2>{{.Operation}}</h2>
+{{range .Tables}}<table>
+<tr><th>{{.Title}}</th><th>Count</th><th>Errors</th><th>Throughput (MB/s)</th>{{range $.Percentiles}}<th>{{label .}} (s)</th>{{end}}</tr>
+{{range .Rows}}<tr><td>{{.Name}}</td><td>{{.Count}}</td><td>{{.Errors}}</td><td>{{printf "%0.2f" .Throughput}}</td>{{range .Percentiles}}<td>{{printf "%0.3f" .Seconds}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}{{range .Charts}}{{.}}
+{{end}}{{end}}<h2>Errors</h2>
+<table>
+<tr><th>Operation</th><th>Errors</th><th>Error rate</th></tr>
+{{range .Operations}}<tr><td>{{.Operation}}</td><td>{{.Errors}}</td><td>{{printf "%0.2f%%" (errorRate .OperationResult)}}</td></tr>
+{{end}}</table>
+{{range .Operations}}{{if .ErrorClasses}}<h3>{{.Operation}} errors</h3>
+<table>
+<tr><th>Class</th><th>HTTP status</th><th>Count</th><th>Example</th></tr>
+{{range .ErrorClasses}}<tr><td>{{.Class}}</td><td>{{.HTTPStatus}}</td><td>{{.Count}}</td><td>{{.Example}}</td></tr>
+{{end}}</table>
+{{end}}{{end}}
+</body>
+</html>
+`))
+
+func errorRate(o OperationResult) float64 {
+	if o.Count+o.Errors == 0 {
+		return 0
+	}
+	return float64(o.Errors) / float64(o.Count+o.Errors) * 100
+}
+
+// WriteHTML writes a self-contained HTML report of the result to w
+func (r *Result) WriteHTML(w io.Writer) error {
+	view := struct {
+		Result      *Result
+		Percentiles []float64
+		Operations  []htmlOperation
+	}{Result: r, Percentiles: percentiles}
+	for _, o := range r.Operations {
+		charts := []template.HTML{
+			throughputChart(o).svg(),
+			latencyCDFChart(o).svg(),
+			latencyHistogramChart(o).svg(),
+		}
+		if len(o.CorrectedHistogram) > 0 {
+			charts = append(charts, correctedCDFChart(o).svg())
+		}
+		if len(o.TTFBHistogram) > 0 {
+			charts = append(charts, ttfbCDFChart(o).svg())
+		}
+		var tables []htmlTable
+		if len(o.Mix) > 0 {
+			tables = append(tables, htmlTable{"Operation", o.Mix})
+		}
+		if o.Warmup != nil {
+			tables = append(tables, htmlTable{"Warm-up", []Breakdown{*o.Warmup}})
+		}
+		if len(o.Sizes) > 0 {
+			tables = append(tables, htmlTable{"Object size", o.Sizes})
+		}
+		if len(o.Endpoints) > 0 {
+			tables = append(tables, htmlTable{"Endpoint", o.Endpoints})
+		}
+		view.Operations = append(view.Operations, htmlOperation{o, tables, charts})
+	}
+	return htmlTemplate.Execute(w, view)
+}
+
+func writeHTMLReport(path string, result *Result) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = result.WriteHTML(file)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,88 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"fmt"
+	"time"
+)
+
+// Interval summarizes the responses received during one reporting window
+type Interval struct {
+	StartTime  time.Time `json:"startTime"`
+	Duration   float64   `json:"durationSeconds"`
+	Count      int       `json:"count"`
+	Bytes      int64     `json:"bytes"`
+	Errors     int       `json:"errors"`
+	OpsPerSec  float64   `json:"opsPerSec"`
+	Throughput float64   `json:"throughputMBps"`
+	P50        float64   `json:"p50Seconds"`
+	P99        float64   `json:"p99Seconds"`
+}
+
+// window accumulates responses until the next interval report
+type window struct {
+	start   time.Time
+	bytes   int64
+	errors  int
+	latency *latency
+}
+
+func newWindow(start time.Time, precision int) *window {
+	return &window{start: start, latency: newLatency(precision)}
+}
+
+func (w *window) add(resp response) {
+	if resp.err != nil {
+		w.errors++
+		return
+	}
+	w.bytes += resp.bytes
+	w.latency.record(resp.duration)
+}
+
+func (w *window) interval(end time.Time) Interval {
+	elapsed := end.Sub(w.start).Seconds()
+	interval := Interval{
+		StartTime: w.start,
+		Duration:  elapsed,
+		Count:     int(w.latency.count()),
+		Bytes:     w.bytes,
+		Errors:    w.errors,
+	}
+	// An empty window has no rate, and NaN can't be encoded in JSON
+	if elapsed > 0 {
+		interval.OpsPerSec = float64(w.latency.count()) / elapsed
+		interval.Throughput = (float64(w.bytes) / (1024 * 1024)) / elapsed
+	}
+	if interval.Count > 0 {
+		interval.P50 = w.latency.percentile(50)
+		interval.P99 = w.latency.percentile(99)
+	}
+	return interval
+}
+
+// format renders the interval as a progress line, where elapsed is the
+// time since the start of the operation
+func (i Interval) format(op string, elapsed time.Duration) string {
+	return fmt.Sprintf("[%7.1fs] %s: %0.1f ops/s, %0.2f MB/s, p50 %0.3f s, p99 %0.3f s, %d errors",
+		elapsed.Seconds(), op, i.OpsPerSec, i.Throughput, i.P50, i.P99, i.Errors)
+}
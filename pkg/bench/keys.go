@@ -0,0 +1,204 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+const (
+	sequentialKeys = "sequential"
+	randomKeys     = "random"
+	zipfKeys       = "zipf"
+	hotspotKeys    = "hotspot"
+)
+
+// keyPattern describes how keys are picked for reads
+type keyPattern struct {
+	name string
+	// theta is the skew of zipf patterns, higher values being more skewed
+	theta float64
+	// hotOps percent of the operations target hotKeys percent of the keys
+	hotOps  float64
+	hotKeys float64
+}
+
+// parseKeyPattern parses one of the following:
+//
+//	sequential         keys in order
+//	random             keys uniformly at random
+//	zipf:0.99          zipfian keys with the given theta, key 0 being the hottest
+//	hotspot:90,10      90% of the operations on the first 10% of the keys
+func parseKeyPattern(s string) (*keyPattern, error) {
+	parts := strings.SplitN(s, ":", 2)
+	p := &keyPattern{name: strings.ToLower(parts[0])}
+	switch p.name {
+	case sequentialKeys, randomKeys:
+		if len(parts) == 2 {
+			return nil, fmt.Errorf("keyPattern(%s) takes no parameters", s)
+		}
+	case zipfKeys:
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("keyPattern(%s) needs a theta such as zipf:0.99", s)
+		}
+		theta, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || theta <= 0 || theta >= 1 {
+			return nil, fmt.Errorf("keyPattern(%s) needs a theta between 0 and 1 exclusive", s)
+		}
+		p.theta = theta
+	case hotspotKeys:
+		var values []string
+		if len(parts) == 2 {
+			values = strings.Split(parts[1], ",")
+		}
+		if len(values) != 2 {
+			return nil, fmt.Errorf("keyPattern(%s) needs operation and key percentages such as hotspot:90,10", s)
+		}
+		ops, err := strconv.ParseFloat(values[0], 64)
+		if err != nil || ops < 0 || ops > 100 {
+			return nil, fmt.Errorf("keyPattern(%s) needs an operation percentage between 0 and 100", s)
+		}
+		keys, err := strconv.ParseFloat(values[1], 64)
+		if err != nil || keys <= 0 || keys > 100 {
+			return nil, fmt.Errorf("keyPattern(%s) needs a key percentage between 0 and 100", s)
+		}
+		p.hotOps, p.hotKeys = ops, keys
+	default:
+		return nil, fmt.Errorf("keyPattern(%s) needs to be one of sequential, random, zipf:THETA or hotspot:OPS,KEYS", s)
+	}
+	return p, nil
+}
+
+func (p *keyPattern) String() string {
+	switch p.name {
+	case zipfKeys:
+		return fmt.Sprintf("%s:%g", p.name, p.theta)
+	case hotspotKeys:
+		return fmt.Sprintf("%s:%g,%g", p.name, p.hotOps, p.hotKeys)
+	}
+	return p.name
+}
+
+// keyChooser picks key indexes below the number of keys it was created for
+type keyChooser interface {
+	next(rng *rand.Rand) uint
+}
+
+// chooser returns a keyChooser over n keys
+func (p *keyPattern) chooser(n uint) keyChooser {
+	switch p.name {
+	case randomKeys:
+		return randomChooser(n)
+	case zipfKeys:
+		return newZipfChooser(n, p.theta)
+	case hotspotKeys:
+		hot := uint(float64(n) * p.hotKeys / 100)
+		if hot < 1 {
+			hot = 1
+		}
+		return hotspotChooser{n: n, hot: hot, hotOps: p.hotOps / 100}
+	}
+	return &sequentialChooser{n: n}
+}
+
+type sequentialChooser struct {
+	n uint
+	i uint
+}
+
+func (c *sequentialChooser) next(rng *rand.Rand) uint {
+	index := c.i % c.n
+	c.i++
+	return index
+}
+
+type randomChooser uint
+
+func (c randomChooser) next(rng *rand.Rand) uint {
+	return uint(rng.Int63n(int64(c)))
+}
+
+type hotspotChooser struct {
+	n      uint
+	hot    uint
+	hotOps float64
+}
+
+func (c hotspotChooser) next(rng *rand.Rand) uint {
+	if c.hot >= c.n || rng.Float64() < c.hotOps {
+		return uint(rng.Int63n(int64(c.hot)))
+	}
+	return c.hot + uint(rng.Int63n(int64(c.n-c.hot)))
+}
+
+// zipfChooser implements the zipfian generator from "Quickly Generating
+// Billion-Record Synthetic Databases" by Gray et al, which unlike
+// rand.Zipf supports a theta below 1
+type zipfChooser struct {
+	n     uint
+	theta float64
+	alpha float64
+	zetan float64
+	eta   float64
+}
+
+func newZipfChooser(n uint, theta float64) *zipfChooser {
+	zeta2 := zeta(2, theta)
+	zetan := zeta(n, theta)
+	return &zipfChooser{
+		n:     n,
+		theta: theta,
+		alpha: 1 / (1 - theta),
+		zetan: zetan,
+		eta:   (1 - math.Pow(2/float64(n), 1-theta)) / (1 - zeta2/zetan),
+	}
+}
+
+func zeta(n uint, theta float64) float64 {
+	sum := 0.0
+	for i := uint(1); i <= n; i++ {
+		sum += 1 / math.Pow(float64(i), theta)
+	}
+	return sum
+}
+
+func (c *zipfChooser) next(rng *rand.Rand) uint {
+	if c.n < 2 {
+		return 0
+	}
+	u := rng.Float64()
+	uz := u * c.zetan
+	if uz < 1 {
+		return 0
+	}
+	if uz < 1+math.Pow(0.5, c.theta) {
+		return 1
+	}
+	index := uint(float64(c.n) * math.Pow(c.eta*u-c.eta+1, c.alpha))
+	if index >= c.n {
+		index = c.n - 1
+	}
+	return index
+}
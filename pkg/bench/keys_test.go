@@ -0,0 +1,121 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestParseKeyPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+		error   bool
+	}{
+		{"sequential", "sequential", false},
+		{"Random", "random", false},
+		{"zipf:0.99", "zipf:0.99", false},
+		{"hotspot:90,10", "hotspot:90,10", false},
+		{"random:1", "", true},
+		{"zipf", "", true},
+		{"zipf:1", "", true},
+		{"zipf:0", "", true},
+		{"hotspot:90", "", true},
+		{"hotspot:101,10", "", true},
+		{"hotspot:90,0", "", true},
+		{"gaussian", "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			p, err := parseKeyPattern(test.pattern)
+			if test.error {
+				if err == nil {
+					t.Errorf("parseKeyPattern(%q) = %s, want an error", test.pattern, p)
+				}
+				return
+			}
+			if err != nil || p.String() != test.want {
+				t.Errorf("parseKeyPattern(%q) = %v, %v, want %s", test.pattern, p, err, test.want)
+			}
+		})
+	}
+}
+
+func TestKeyChoosers(t *testing.T) {
+	const keys, draws = 1000, 200000
+	tests := []struct {
+		pattern string
+		// share is the expected share of operations on the first 10% of keys
+		share float64
+		// first is the expected share of operations on key 0
+		first float64
+	}{
+		{"random", 0.1, 0.001},
+		{"zipf:0.99", zeta(keys/10, 0.99) / zeta(keys, 0.99), 1 / zeta(keys, 0.99)},
+		{"zipf:0.5", zeta(keys/10, 0.5) / zeta(keys, 0.5), 1 / zeta(keys, 0.5)},
+		{"hotspot:90,10", 0.9, 0.009},
+		{"hotspot:50,50", 0.1, 0.001},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			p, err := parseKeyPattern(test.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			chooser := p.chooser(keys)
+			rng := rand.New(rand.NewSource(1))
+			hot, first := 0, 0
+			for i := 0; i < draws; i++ {
+				index := chooser.next(rng)
+				if index >= keys {
+					t.Fatalf("next() = %d, want an index below %d", index, keys)
+				}
+				if index < keys/10 {
+					hot++
+				}
+				if index == 0 {
+					first++
+				}
+			}
+			// The zipfian generator only approximates the tail of the distribution
+			if share := float64(hot) / draws; math.Abs(share-test.share) > 0.02 {
+				t.Errorf("%s put %0.3f of the operations on 10%% of the keys, want %0.3f", test.pattern, share, test.share)
+			}
+			// Allow for four standard deviations of sampling noise
+			tolerance := 4 * math.Sqrt(test.first/draws)
+			if share := float64(first) / draws; math.Abs(share-test.first) > tolerance {
+				t.Errorf("%s put %0.4f of the operations on key 0, want %0.4f", test.pattern, share, test.first)
+			}
+		})
+	}
+}
+
+func TestSequentialChooser(t *testing.T) {
+	p, _ := parseKeyPattern("sequential")
+	chooser := p.chooser(3)
+	for i, want := range []uint{0, 1, 2, 0, 1} {
+		if index := chooser.next(nil); index != want {
+			t.Errorf("next() #%d = %d, want %d", i, index, want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+)
+
+const (
+	// Operation times are recorded in nanoseconds, as is customary for
+	// HdrHistogram logs, with a resolution of one microsecond
+	minTrackableLatency = int64(time.Microsecond)
+	maxTrackableLatency = int64(time.Hour)
+	// Runs with up to this many samples report exact percentiles
+	exactSampleLimit = 100000
+	// defaultPrecision is the number of significant value digits kept by
+	// the histograms
+	defaultPrecision = 3
+)
+
+// latency records operation times in an HDR histogram. The first
+// exactSampleLimit samples are also kept verbatim so that small runs can
+// report exact percentiles.
+type latency struct {
+	histogram *hdrhistogram.Histogram
+	exact     []float64
+	sorted    bool
+}
+
+func newLatency(precision int) *latency {
+	return &latency{
+		histogram: hdrhistogram.New(minTrackableLatency, maxTrackableLatency, precision),
+		exact:     make([]float64, 0),
+	}
+}
+
+func (l *latency) record(d time.Duration) {
+	value := int64(d)
+	if value < minTrackableLatency {
+		value = minTrackableLatency
+	} else if value > maxTrackableLatency {
+		value = maxTrackableLatency
+	}
+	l.histogram.RecordValue(value)
+	if l.exact == nil {
+		return
+	}
+	if len(l.exact) == exactSampleLimit {
+		l.exact = nil
+		return
+	}
+	l.exact = append(l.exact, d.Seconds())
+	l.sorted = false
+}
+
+func (l *latency) count() int64 {
+	return l.histogram.TotalCount()
+}
+
+// percentile returns the operation time in seconds at the given percentile
+func (l *latency) percentile(p float64) float64 {
+	if l.exact != nil {
+		if !l.sorted {
+			sort.Float64s(l.exact)
+			l.sorted = true
+		}
+		i := 0
+		if p >= 100 {
+			i = len(l.exact) - 1
+		} else if p > 0 {
+			i = int(p / 100 * float64(len(l.exact)))
+		}
+		return l.exact[i]
+	}
+	var value int64
+	switch {
+	case p >= 100:
+		value = l.histogram.Max()
+	case p <= 0:
+		value = l.histogram.Min()
+	default:
+		value = l.histogram.ValueAtQuantile(p)
+	}
+	return float64(value) / float64(time.Second)
+}
+
+// buckets spreads the recorded operation times over n logarithmically sized
+// buckets between the minimum and the maximum
+func (l *latency) buckets(n int) []Bucket {
+	if l.count() == 0 {
+		return nil
+	}
+	min, max := float64(l.histogram.Min()), float64(l.histogram.Max())
+	if max <= min {
+		return []Bucket{{max / float64(time.Second), l.count()}}
+	}
+	ratio := math.Pow(max/min, 1/float64(n))
+	buckets := make([]Bucket, n)
+	for i := range buckets {
+		buckets[i].UpperBound = min * math.Pow(ratio, float64(i+1)) / float64(time.Second)
+	}
+	for _, bar := range l.histogram.Distribution() {
+		if bar.Count == 0 {
+			continue
+		}
+		i := int(math.Log(float64(bar.From)/min) / math.Log(ratio))
+		if i < 0 {
+			i = 0
+		} else if i >= n {
+			i = n - 1
+		}
+		buckets[i].Count += bar.Count
+	}
+	return buckets
+}
+
+// writeHistogramLog saves the histogram of every report in the
+// HdrHistogram interval log format, tagged with the operation name
+func writeHistogramLog(path string, start time.Time, reports []report) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = writeHistogramLogTo(file, start, reports)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func writeHistogramLogTo(w io.Writer, start time.Time, reports []report) error {
+	log := hdrhistogram.NewHistogramLogWriter(w)
+	if err := log.OutputLogFormatVersion(); err != nil {
+		return err
+	}
+	if err := log.OutputStartTime(start.UnixNano() / int64(time.Millisecond)); err != nil {
+		return err
+	}
+	if err := log.OutputLegend(); err != nil {
+		return err
+	}
+	for _, r := range reports {
+		if r.latency == nil {
+			continue
+		}
+		payload, err := r.latency.histogram.Encode(hdrhistogram.V2CompressedEncodingCookieBase)
+		if err != nil {
+			return err
+		}
+		// Timestamps are in seconds relative to the start time and the
+		// maximum is in milliseconds
+		_, err = fmt.Fprintf(w, "Tag=%s,%.3f,%.3f,%.3f,%s\n",
+			r.Operation,
+			r.startTime.Sub(start).Seconds(),
+			r.totalDuration.Seconds(),
+			float64(r.latency.histogram.Max())/float64(time.Millisecond),
+			payload)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+)
+
+func TestLatencyPercentile(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples int
+		// tolerance is the relative error allowed, which is 0 for exact
+		// percentiles
+		tolerance float64
+	}{
+		{"exact", 1000, 0},
+		{"at the limit", exactSampleLimit, 0},
+		{"histogram", exactSampleLimit + 1, 0.001},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := newLatency(defaultPrecision)
+			// Recorded in reverse so that exact percentiles need sorting
+			for i := test.samples; i > 0; i-- {
+				l.record(time.Duration(i) * time.Millisecond)
+			}
+			if exact := l.exact != nil; exact != (test.tolerance == 0) {
+				t.Fatalf("exact samples kept = %v after %d samples", exact, test.samples)
+			}
+			n := float64(test.samples)
+			for _, p := range []struct{ percentile, want float64 }{
+				{0, 0.001},
+				{50, (math.Floor(n/2) + 1) / 1000},
+				{99, (math.Floor(n*0.99) + 1) / 1000},
+				{100, n / 1000},
+			} {
+				got := l.percentile(p.percentile)
+				if math.Abs(got-p.want) > p.want*test.tolerance {
+					t.Errorf("percentile(%g) = %g, want %g", p.percentile, got, p.want)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteHistogramLog(t *testing.T) {
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	var reports []report
+	for i, op := range []string{writeOp, readOp} {
+		r := report{
+			Operation:     op,
+			latency:       newLatency(defaultPrecision),
+			startTime:     start.Add(time.Duration(i+1) * time.Second),
+			totalDuration: 2 * time.Second,
+		}
+		for j := 1; j <= 100*(i+1); j++ {
+			r.latency.record(time.Duration(j) * time.Millisecond)
+		}
+		reports = append(reports, r)
+	}
+	var log bytes.Buffer
+	if err := writeHistogramLogTo(&log, start, reports); err != nil {
+		t.Fatal(err)
+	}
+	reader := hdrhistogram.NewHistogramLogReader(&log)
+	for i, r := range reports {
+		histogram, err := reader.NextIntervalHistogram()
+		if err != nil || histogram == nil {
+			t.Fatalf("NextIntervalHistogram() #%d = %v, %v", i, histogram, err)
+		}
+		if histogram.Tag() != r.Operation {
+			t.Errorf("histogram #%d is tagged %q, want %q", i, histogram.Tag(), r.Operation)
+		}
+		if histogram.TotalCount() != r.latency.count() || histogram.Max() != r.latency.histogram.Max() {
+			t.Errorf("histogram #%d has %d values up to %d, want %d up to %d", i,
+				histogram.TotalCount(), histogram.Max(), r.latency.count(), r.latency.histogram.Max())
+		}
+		if offset := histogram.StartTimeMs() - start.UnixNano()/int64(time.Millisecond); offset != int64(i+1)*1000 {
+			t.Errorf("histogram #%d starts %d ms after the start, want %d", i, offset, (i+1)*1000)
+		}
+	}
+	if histogram, err := reader.NextIntervalHistogram(); histogram != nil || (err != nil && err != io.EOF) {
+		t.Errorf("NextIntervalHistogram() after the last report = %v, %v", histogram, err)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	successStatus = "success"
+	errorStatus   = "error"
+)
+
+// metrics exports the client-side view of a benchmark to Prometheus. It is
+// updated directly by the client goroutines.
+type metrics struct {
+	operations *prometheus.CounterVec
+	bytes      *prometheus.CounterVec
+	durations  *prometheus.HistogramVec
+	server     *http.Server
+}
+
+// newMetrics serves /metrics on addr until close is called
+func newMetrics(addr string) (*metrics, error) {
+	labels := []string{"operation", "endpoint", "status"}
+	m := &metrics{
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "benchio",
+			Name:      "operations_total",
+			Help:      "Number of completed operations.",
+		}, labels),
+		bytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "benchio",
+			Name:      "bytes_total",
+			Help:      "Number of bytes transferred by completed operations.",
+		}, labels),
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "benchio",
+			Name:      "operation_duration_seconds",
+			Help:      "Time taken by completed operations.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 16),
+		}, labels),
+	}
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(m.operations, m.bytes, m.durations)
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	m.server = &http.Server{Handler: mux}
+	go m.server.Serve(listener)
+	return m, nil
+}
+
+func (m *metrics) observe(resp response) {
+	status := successStatus
+	if resp.err != nil {
+		status = errorStatus
+	}
+	labels := prometheus.Labels{"operation": resp.op, "endpoint": resp.endpoint, "status": status}
+	m.operations.With(labels).Inc()
+	m.bytes.With(labels).Add(float64(resp.bytes))
+	m.durations.With(labels).Observe(resp.duration.Seconds())
+}
+
+func (m *metrics) close() error {
+	return m.server.Close()
+}
@@ -0,0 +1,251 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+func newRand() *rand.Rand {
+	return rand.New(rand.NewSource(time.Now().UnixNano()))
+}
+
+// mixOps maps the operation names accepted in a mix to their operations
+var mixOps = map[string]string{
+	"read":   readOp,
+	"write":  writeOp,
+	"head":   headOp,
+	"delete": deleteOp,
+	"list":   listOp,
+	"copy":   copyOp,
+}
+
+type weightedOp struct {
+	op     string
+	weight float64
+}
+
+// mix picks operations at random according to their weights
+type mix []weightedOp
+
+// parseMix parses a comma separated list of operation=weight pairs such as
+// "read=70,write=20,head=5,delete=5"
+func parseMix(s string) (mix, error) {
+	var m mix
+	for _, pair := range strings.Split(s, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("mix entry %q needs to be of the form operation=weight", pair)
+		}
+		op, ok := mixOps[strings.ToLower(parts[0])]
+		if !ok {
+			return nil, fmt.Errorf("mix operation %q needs to be one of read, write, head, delete, list or copy", parts[0])
+		}
+		weight, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("mix weight %q needs to be a non-negative number", parts[1])
+		}
+		m = append(m, weightedOp{op, weight})
+	}
+	if m.total() == 0 {
+		return nil, fmt.Errorf("mix(%s) needs at least one operation with a positive weight", s)
+	}
+	return m, nil
+}
+
+func (m mix) total() float64 {
+	total := 0.0
+	for _, w := range m {
+		total += w.weight
+	}
+	return total
+}
+
+func (m mix) pick(rng *rand.Rand) string {
+	n := rng.Float64() * m.total()
+	for _, w := range m {
+		if n < w.weight {
+			return w.op
+		}
+		n -= w.weight
+	}
+	return m[len(m)-1].op
+}
+
+func (m mix) String() string {
+	pairs := make([]string, len(m))
+	for i, w := range m {
+		pairs[i] = fmt.Sprintf("%s=%g", strings.ToLower(w.op), w.weight)
+	}
+	return strings.Join(pairs, ",")
+}
+
+// indexSet is a set of key indexes supporting constant time random picks
+type indexSet struct {
+	items     []uint
+	positions map[uint]int
+}
+
+func newIndexSet() indexSet {
+	return indexSet{positions: make(map[uint]int)}
+}
+
+func (s *indexSet) add(i uint) {
+	if _, ok := s.positions[i]; ok {
+		return
+	}
+	s.positions[i] = len(s.items)
+	s.items = append(s.items, i)
+}
+
+func (s *indexSet) remove(i uint) {
+	position, ok := s.positions[i]
+	if !ok {
+		return
+	}
+	last := s.items[len(s.items)-1]
+	s.items[position] = last
+	s.positions[last] = position
+	s.items = s.items[:len(s.items)-1]
+	delete(s.positions, i)
+}
+
+func (s *indexSet) random(rng *rand.Rand) (uint, bool) {
+	if len(s.items) == 0 {
+		return 0, false
+	}
+	return s.items[rng.Intn(len(s.items))], true
+}
+
+// keySet tracks which keys exist while a mixed workload runs, so that
+// reads and deletes target live objects and writes recreate deleted ones.
+// Keys with requests in flight are kept out of idle, so that they aren't
+// deleted or overwritten under a pending read.
+type keySet struct {
+	mu   sync.Mutex
+	live indexSet
+	dead indexSet
+	idle indexSet
+	busy map[uint]int
+}
+
+func newKeySet(live, total uint) *keySet {
+	k := &keySet{live: newIndexSet(), dead: newIndexSet(), idle: newIndexSet(), busy: make(map[uint]int)}
+	for i := uint(0); i < total; i++ {
+		if i < live {
+			k.live.add(i)
+			k.idle.add(i)
+		} else {
+			k.dead.add(i)
+		}
+	}
+	return k
+}
+
+// next picks the key for op, which may be changed to a write if there's no
+// live key left to read or delete. Keys are picked with the chooser when
+// given, falling back to a random key if the chosen one isn't available.
+func (k *keySet) next(op string, rng *rand.Rand, chooser keyChooser) (string, uint) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if op != writeOp {
+		keys := &k.live
+		if op == deleteOp {
+			keys = &k.idle
+		}
+		index, ok := uint(0), false
+		if chooser != nil {
+			index = chooser.next(rng)
+			_, ok = keys.positions[index]
+		}
+		if !ok {
+			index, ok = keys.random(rng)
+		}
+		if ok {
+			switch op {
+			case deleteOp:
+				// Stop reading the key as soon as its deletion is submitted
+				k.live.remove(index)
+				k.idle.remove(index)
+			case listOp:
+				// Lists only start after the key, which doesn't need to exist
+			default:
+				k.acquire(index)
+			}
+			return op, index
+		}
+		op = writeOp
+	}
+	index, ok := k.dead.random(rng)
+	if !ok {
+		// Overwrite a key that isn't in use, unless they all are
+		if index, ok = k.idle.random(rng); !ok {
+			index, _ = k.live.random(rng)
+		}
+	}
+	k.acquire(index)
+	return op, index
+}
+
+func (k *keySet) acquire(index uint) {
+	k.busy[index]++
+	k.idle.remove(index)
+}
+
+func (k *keySet) release(index uint) {
+	if k.busy[index]--; k.busy[index] > 0 {
+		return
+	}
+	delete(k.busy, index)
+	if _, ok := k.live.positions[index]; ok {
+		k.idle.add(index)
+	}
+}
+
+// completed updates the set once a request is done
+func (k *keySet) completed(resp response) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	switch resp.op {
+	case writeOp:
+		if resp.err == nil {
+			k.dead.remove(resp.index)
+			k.live.add(resp.index)
+		}
+		k.release(resp.index)
+	case deleteOp:
+		if resp.err == nil {
+			k.dead.add(resp.index)
+			break
+		}
+		// The object is still there after a failed delete
+		k.live.add(resp.index)
+		k.idle.add(resp.index)
+	case listOp:
+	default:
+		k.release(resp.index)
+	}
+}
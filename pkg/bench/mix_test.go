@@ -0,0 +1,125 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestParseMix(t *testing.T) {
+	tests := []struct {
+		mix   string
+		want  string
+		error bool
+	}{
+		{"read=70,write=20,head=5,delete=5", "read=70,write=20,head=5,delete=5", false},
+		{" READ=1 , list=0.5,copy=0", "read=1,list=0.5,copy=0", false},
+		{"read=0,write=0", "", true},
+		{"read=-1,write=1", "", true},
+		{"read=abc", "", true},
+		{"read", "", true},
+		{"scan=1", "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.mix, func(t *testing.T) {
+			m, err := parseMix(test.mix)
+			if test.error {
+				if err == nil {
+					t.Errorf("parseMix(%q) = %s, want an error", test.mix, m)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseMix(%q) failed: %v", test.mix, err)
+			}
+			if m.String() != test.want {
+				t.Errorf("parseMix(%q) = %s, want %s", test.mix, m, test.want)
+			}
+		})
+	}
+}
+
+func TestMixPick(t *testing.T) {
+	m, _ := parseMix("read=3,write=1,head=0")
+	rng := rand.New(rand.NewSource(1))
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		counts[m.pick(rng)]++
+	}
+	if counts[headOp] != 0 {
+		t.Errorf("pick() chose %s %d times with a weight of 0", headOp, counts[headOp])
+	}
+	if reads := float64(counts[readOp]) / 10000; reads < 0.72 || reads > 0.78 {
+		t.Errorf("pick() chose %s %0.3f of the time, want 0.75", readOp, reads)
+	}
+}
+
+func TestKeySetInFlight(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	keys := newKeySet(1, 2)
+	// The only live key is being read, so the delete becomes a write
+	_, read := keys.next(readOp, rng, nil)
+	op, written := keys.next(deleteOp, rng, nil)
+	if op != writeOp || written == read {
+		t.Fatalf("next(%s) = %s %d with key %d being read, want %s of the other key", deleteOp, op, written, read, writeOp)
+	}
+	keys.completed(response{op: writeOp, index: written})
+	op, deleted := keys.next(deleteOp, rng, nil)
+	if op != deleteOp || deleted != written {
+		t.Fatalf("next(%s) = %s %d, want %s %d", deleteOp, op, deleted, deleteOp, written)
+	}
+	// Deleted keys aren't read while their deletion is in flight
+	for i := 0; i < 10; i++ {
+		if _, index := keys.next(headOp, rng, nil); index != read {
+			t.Fatalf("next(%s) = %d while it is deleted", headOp, index)
+		}
+		keys.completed(response{op: headOp, index: read})
+	}
+	keys.completed(response{op: readOp, index: read})
+	keys.completed(response{op: deleteOp, index: deleted})
+	if op, index := keys.next(writeOp, rng, nil); op != writeOp || index != deleted {
+		t.Errorf("next(%s) = %s %d, want the deleted key %d", writeOp, op, index, deleted)
+	}
+	if op, index := keys.next(deleteOp, rng, nil); op != deleteOp || index != read {
+		t.Errorf("next(%s) = %s %d, want %s %d once it isn't read", deleteOp, op, index, deleteOp, read)
+	}
+}
+
+func TestKeySetFailedDelete(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	keys := newKeySet(1, 2)
+	_, deleted := keys.next(deleteOp, rng, nil)
+	keys.completed(response{op: deleteOp, index: deleted, err: errors.New("failed")})
+	// The key still exists, so it is read and deleted again
+	if op, index := keys.next(readOp, rng, nil); op != readOp || index != deleted {
+		t.Errorf("next(%s) = %s %d after a failed delete, want %s %d", readOp, op, index, readOp, deleted)
+	}
+	keys.completed(response{op: readOp, index: deleted})
+	if op, index := keys.next(deleteOp, rng, nil); op != deleteOp || index != deleted {
+		t.Errorf("next(%s) = %s %d after a failed delete, want %s %d", deleteOp, op, index, deleteOp, deleted)
+	}
+	keys.completed(response{op: deleteOp, index: deleted})
+	if op, index := keys.next(readOp, rng, nil); op != writeOp {
+		t.Errorf("next(%s) = %s %d once every key is deleted, want %s", readOp, op, index, writeOp)
+	}
+}
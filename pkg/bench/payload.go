@@ -0,0 +1,305 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+)
+
+const (
+	randomData = "random"
+	zeroData   = "zero"
+	textData   = "text"
+	// payloadBlockSize is the unit of compression and deduplication of
+	// generated content
+	payloadBlockSize = 4096
+	// dedupPoolSize is the number of distinct blocks shared between objects
+	// when deduplication is requested
+	dedupPoolSize = 256
+)
+
+// textWords are the words text-like content is made of
+var textWords = []string{
+	"the", "of", "and", "to", "in", "is", "that", "for", "it", "as", "was", "with",
+	"be", "by", "on", "not", "he", "this", "are", "or", "his", "from", "at", "which",
+	"but", "have", "an", "had", "they", "you", "were", "their", "one", "all", "we", "can",
+	"her", "has", "there", "been", "if", "more", "when", "will", "would", "who", "so", "no",
+	"object", "storage", "bucket", "request", "latency", "throughput", "client", "server",
+	"data", "read", "write", "benchmark", "endpoint", "network",
+}
+
+// DataPattern describes the content generated for objects
+type DataPattern struct {
+	// Kind is random, zero or text
+	Kind string
+	// Compression is the ratio random content should compress by
+	Compression float64
+	// Dedup is the ratio content should deduplicate by across objects
+	Dedup float64
+}
+
+// NewDataPattern validates the given pattern, filling in its defaults
+func NewDataPattern(kind string, compression, dedup float64) (DataPattern, error) {
+	p := DataPattern{Kind: kind, Compression: compression, Dedup: dedup}
+	if p.Kind == "" {
+		p.Kind = randomData
+	}
+	if p.Kind != randomData && p.Kind != zeroData && p.Kind != textData {
+		return p, fmt.Errorf("dataPattern(%s) needs to be one of %q, %q or %q", kind, randomData, zeroData, textData)
+	}
+	if p.Compression == 0 {
+		p.Compression = 1
+	}
+	if p.Compression < 1 {
+		return p, fmt.Errorf("compressionRatio(%g) needs to be at least 1", compression)
+	}
+	if p.Dedup == 0 {
+		p.Dedup = 1
+	}
+	if p.Dedup < 1 {
+		return p, fmt.Errorf("dedupRatio(%g) needs to be at least 1", dedup)
+	}
+	// Zero content compresses and deduplicates entirely, and text content
+	// compresses as text does
+	if p.Kind != randomData && p.Compression != 1 {
+		return p, fmt.Errorf("compressionRatio(%g) needs the %q dataPattern", compression, randomData)
+	}
+	if p.Kind == zeroData && p.Dedup != 1 {
+		return p, fmt.Errorf("dedupRatio(%g) can't be used with the %q dataPattern", dedup, zeroData)
+	}
+	return p, nil
+}
+
+// pattern returns the pattern of the content generated for objects, which
+// validate has already checked
+func (conf *Config) pattern() DataPattern {
+	return DataPattern{conf.DataPattern, conf.CompressionRatio, conf.DedupRatio}
+}
+
+func (p DataPattern) String() string {
+	return fmt.Sprintf("%s, compression ratio %g, dedup ratio %g", p.Kind, p.Compression, p.Dedup)
+}
+
+// Payload returns the content of the named object
+func (p DataPattern) Payload(seed int64, key string, size int64) io.ReadSeeker {
+	return newPayload(p, seed, key, size)
+}
+
+// payload generates the content of an object from its key and a seed as it
+// is read, so that objects of any size need no buffer, every object is
+// unique and its content can be reproduced when it is read back
+type payload struct {
+	pattern DataPattern
+	seed    uint64
+	base    uint64
+	size    int64
+	offset  int64
+	block   []byte
+}
+
+func newPayload(pattern DataPattern, seed int64, key string, size int64) *payload {
+	hash := fnv.New64a()
+	hash.Write([]byte(key))
+	return &payload{
+		pattern: pattern,
+		seed:    uint64(seed),
+		base:    hash.Sum64() ^ uint64(seed),
+		size:    size,
+	}
+}
+
+// splitmix64 scrambles x into a well distributed pseudo-random word
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// fill writes the content found at offset into b
+func (p *payload) fill(b []byte, offset int64) {
+	for len(b) > 0 {
+		index, skip := offset/payloadBlockSize, int(offset%payloadBlockSize)
+		if skip == 0 && len(b) >= payloadBlockSize {
+			p.generate(b[:payloadBlockSize], index)
+			b, offset = b[payloadBlockSize:], offset+payloadBlockSize
+			continue
+		}
+		if p.block == nil {
+			p.block = make([]byte, payloadBlockSize)
+		}
+		p.generate(p.block, index)
+		n := copy(b, p.block[skip:])
+		b, offset = b[n:], offset+int64(n)
+	}
+}
+
+// generate writes the block with the given index into b. Blocks only
+// depend on their seed, which is shared between objects for the part of
+// the blocks that deduplicate.
+func (p *payload) generate(b []byte, index int64) {
+	seed := splitmix64(p.base + uint64(index))
+	if p.pattern.Dedup > 1 && float64(seed>>11)/(1<<53) >= 1/p.pattern.Dedup {
+		seed = splitmix64(p.seed + seed%dedupPoolSize)
+	}
+	switch p.pattern.Kind {
+	case zeroData:
+		for i := range b {
+			b[i] = 0
+		}
+	case textData:
+		generateText(b, seed)
+	default:
+		// Only the start of the block is random, the rest compresses away
+		random := int(math.Ceil(float64(len(b)) / p.pattern.Compression))
+		random = (random + 7) &^ 7
+		for i := 0; i < len(b); i += 8 {
+			if i < random {
+				binary.LittleEndian.PutUint64(b[i:], splitmix64(seed+uint64(i)))
+			} else {
+				binary.LittleEndian.PutUint64(b[i:], 0)
+			}
+		}
+	}
+}
+
+// textTable holds random words that text blocks are copied from, a few
+// bytes at a time, which is much faster than picking every word
+var textTable = newTextTable(64 * 1024)
+
+// textSegment is the number of bytes copied at once from textTable
+const textSegment = 32
+
+func newTextTable(size int) []byte {
+	// The table is padded so that any offset below size has a full segment
+	var table []byte
+	for i := uint64(0); len(table) < size+textSegment; i++ {
+		word := splitmix64(i)
+		table = append(table, textWords[word%uint64(len(textWords))]...)
+		if word>>60 == 0 {
+			table = append(table, '\n')
+		} else {
+			table = append(table, ' ')
+		}
+	}
+	return table
+}
+
+// generateText fills b with segments of textTable at random offsets
+func generateText(b []byte, seed uint64) {
+	size := uint64(len(textTable) - textSegment)
+	for i := 0; i < len(b); i += textSegment {
+		offset := splitmix64(seed+uint64(i)) % size
+		copy(b[i:], textTable[offset:offset+textSegment])
+	}
+}
+
+func (p *payload) Read(b []byte) (int, error) {
+	if p.offset >= p.size {
+		return 0, io.EOF
+	}
+	if remaining := p.size - p.offset; int64(len(b)) > remaining {
+		b = b[:remaining]
+	}
+	p.fill(b, p.offset)
+	p.offset += int64(len(b))
+	return len(b), nil
+}
+
+// ReadAt lets multipart uploads read the parts without buffering them
+func (p *payload) ReadAt(b []byte, off int64) (int, error) {
+	if off >= p.size {
+		return 0, io.EOF
+	}
+	var err error
+	if remaining := p.size - off; int64(len(b)) > remaining {
+		b, err = b[:remaining], io.EOF
+	}
+	p.fill(b, off)
+	return len(b), err
+}
+
+func (p *payload) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += p.offset
+	case io.SeekEnd:
+		offset += p.size
+	}
+	if offset < 0 {
+		return 0, errors.New("Seek to a negative offset")
+	}
+	p.offset = offset
+	return offset, nil
+}
+
+// integrityError reports an object whose content differs from its payload
+type integrityError struct {
+	key    string
+	offset int64
+}
+
+func (e integrityError) Error() string {
+	return fmt.Sprintf("Content of %s differs from the expected payload at offset %d", e.key, e.offset)
+}
+
+// verifier compares the content read from an object with its payload,
+// starting at the given offset for ranged reads
+type verifier struct {
+	payload  *payload
+	key      string
+	offset   int64
+	expected []byte
+}
+
+func newVerifier(pattern DataPattern, seed int64, key string, offset int64) *verifier {
+	return &verifier{payload: newPayload(pattern, seed, key, 0), key: key, offset: offset}
+}
+
+func (v *verifier) Write(b []byte) (int, error) {
+	n, err := v.WriteAt(b, 0)
+	v.offset += int64(n)
+	return n, err
+}
+
+// WriteAt verifies b as the content found off bytes after the start of the
+// read, which lets the verifier take the parts of multipart downloads
+func (v *verifier) WriteAt(b []byte, off int64) (int, error) {
+	if cap(v.expected) < len(b) {
+		v.expected = make([]byte, len(b))
+	}
+	expected := v.expected[:len(b)]
+	v.payload.fill(expected, v.offset+off)
+	if !bytes.Equal(b, expected) {
+		for i := range b {
+			if b[i] != expected[i] {
+				return i, integrityError{v.key, v.offset + off + int64(i)}
+			}
+		}
+	}
+	return len(b), nil
+}
@@ -0,0 +1,193 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+var testPatterns = []DataPattern{
+	{randomData, 1, 1},
+	{randomData, 3, 2},
+	{zeroData, 1, 1},
+	{textData, 1, 1},
+	{textData, 1, 4},
+}
+
+func TestPayloadReadAtAndSeek(t *testing.T) {
+	const size = 3*payloadBlockSize + 1234
+	for _, pattern := range testPatterns {
+		t.Run(pattern.String(), func(t *testing.T) {
+			all, err := ioutil.ReadAll(newPayload(pattern, 1, "key", size))
+			if err != nil || len(all) != size {
+				t.Fatalf("ReadAll() = %d bytes, %v, want %d", len(all), err, size)
+			}
+			again, _ := ioutil.ReadAll(newPayload(pattern, 1, "key", size))
+			if !bytes.Equal(all, again) {
+				t.Fatalf("payloads of the same key differ")
+			}
+			tests := []struct {
+				offset int64
+				length int
+			}{
+				{0, 10},
+				{100, payloadBlockSize},
+				{payloadBlockSize - 1, 2},
+				{payloadBlockSize, 2 * payloadBlockSize},
+				{size - 10, 10},
+			}
+			for _, test := range tests {
+				p := newPayload(pattern, 1, "key", size)
+				b := make([]byte, test.length)
+				if n, err := p.ReadAt(b, test.offset); n != test.length || (err != nil && err != io.EOF) {
+					t.Fatalf("ReadAt(%d) = %d, %v", test.offset, n, err)
+				}
+				want := all[test.offset : test.offset+int64(test.length)]
+				if !bytes.Equal(b, want) {
+					t.Errorf("ReadAt(%d) differs from Read", test.offset)
+				}
+				if position, err := p.Seek(test.offset, io.SeekStart); err != nil || position != test.offset {
+					t.Fatalf("Seek(%d) = %d, %v", test.offset, position, err)
+				}
+				if _, err := io.ReadFull(p, b); err != nil || !bytes.Equal(b, want) {
+					t.Errorf("Read after Seek(%d) differs from Read: %v", test.offset, err)
+				}
+			}
+			p := newPayload(pattern, 1, "key", size)
+			if end, _ := p.Seek(-10, io.SeekEnd); end != size-10 {
+				t.Errorf("Seek(-10, io.SeekEnd) = %d, want %d", end, size-10)
+			}
+			if rest, _ := ioutil.ReadAll(p); !bytes.Equal(rest, all[size-10:]) {
+				t.Errorf("Read after Seek(-10, io.SeekEnd) differs from Read")
+			}
+			if _, err := p.Seek(-1, io.SeekStart); err == nil {
+				t.Errorf("Seek(-1) succeeded")
+			}
+			if n, err := p.ReadAt(make([]byte, 20), size-10); n != 10 || err != io.EOF {
+				t.Errorf("ReadAt() past the end = %d, %v, want 10, io.EOF", n, err)
+			}
+		})
+	}
+}
+
+func TestPayloadUniqueness(t *testing.T) {
+	read := func(seed int64, key string) []byte {
+		b, _ := ioutil.ReadAll(newPayload(testPatterns[0], seed, key, payloadBlockSize))
+		return b
+	}
+	if bytes.Equal(read(1, "a"), read(1, "b")) {
+		t.Errorf("payloads of different keys are equal")
+	}
+	if bytes.Equal(read(1, "a"), read(2, "a")) {
+		t.Errorf("payloads of different seeds are equal")
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	const size = 2*payloadBlockSize + 100
+	pattern := testPatterns[3]
+	content, _ := ioutil.ReadAll(newPayload(pattern, 1, "key", size))
+	tests := []struct {
+		name string
+		// offset is where the read starts, as for ranged reads
+		offset int64
+		// corrupt is the offset of the byte to flip, or -1
+		corrupt int64
+		seed    int64
+		want    int64
+	}{
+		{"intact", 0, -1, 1, -1},
+		{"intact range", 5000, -1, 1, -1},
+		{"first byte", 0, 0, 1, 0},
+		{"second block", 0, payloadBlockSize + 7, 1, payloadBlockSize + 7},
+		{"last byte", 0, size - 1, 1, size - 1},
+		{"in range", 5000, 6000, 1, 6000},
+		{"other seed", 0, -1, 2, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := append([]byte(nil), content[test.offset:]...)
+			if test.corrupt >= 0 {
+				b[test.corrupt-test.offset] ^= 1
+			}
+			v := newVerifier(pattern, test.seed, "key", test.offset)
+			// Write in uneven pieces to cross block boundaries
+			_, err := io.CopyBuffer(v, bytes.NewReader(b), make([]byte, 1000))
+			checkIntegrity(t, "Write", err, test.want)
+			v = newVerifier(pattern, test.seed, "key", test.offset)
+			_, err = v.WriteAt(b[:1500], 0)
+			if err == nil {
+				_, err = v.WriteAt(b[1500:], 1500)
+			}
+			checkIntegrity(t, "WriteAt", err, test.want)
+		})
+	}
+}
+
+func checkIntegrity(t *testing.T, name string, err error, want int64) {
+	t.Helper()
+	var integrity integrityError
+	switch {
+	case want < 0 && err != nil:
+		t.Errorf("%s() failed: %v", name, err)
+	case want >= 0 && !errors.As(err, &integrity):
+		t.Errorf("%s() = %v, want an integrity error", name, err)
+	case want >= 0 && integrity.offset != want:
+		t.Errorf("%s() failed at offset %d, want %d", name, integrity.offset, want)
+	}
+}
+
+func TestNewDataPattern(t *testing.T) {
+	tests := []struct {
+		kind        string
+		compression float64
+		dedup       float64
+		want        DataPattern
+		error       bool
+	}{
+		{"", 0, 0, DataPattern{randomData, 1, 1}, false},
+		{randomData, 2, 3, DataPattern{randomData, 2, 3}, false},
+		{zeroData, 0, 1, DataPattern{zeroData, 1, 1}, false},
+		{textData, 1, 2, DataPattern{textData, 1, 2}, false},
+		{randomData, 0.5, 0, DataPattern{}, true},
+		{randomData, 0, 0.5, DataPattern{}, true},
+		{zeroData, 2, 0, DataPattern{}, true},
+		{zeroData, 0, 2, DataPattern{}, true},
+		{textData, 2, 0, DataPattern{}, true},
+		{"binary", 0, 0, DataPattern{}, true},
+	}
+	for _, test := range tests {
+		pattern, err := NewDataPattern(test.kind, test.compression, test.dedup)
+		if test.error {
+			if err == nil {
+				t.Errorf("NewDataPattern(%q, %g, %g) = %s, want an error", test.kind, test.compression, test.dedup, pattern)
+			}
+			continue
+		}
+		if err != nil || pattern != test.want {
+			t.Errorf("NewDataPattern(%q, %g, %g) = %s, %v, want %s", test.kind, test.compression, test.dedup, pattern, err, test.want)
+		}
+	}
+}
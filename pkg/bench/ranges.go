@@ -0,0 +1,74 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const (
+	sequentialRanges = "sequential"
+	randomRanges     = "random"
+)
+
+// rangeCursor picks the byte ranges read from each object. Sequential
+// ranges walk every object from its start, wrapping around at its end.
+type rangeCursor struct {
+	length int64
+	random bool
+	next   map[uint]int64
+}
+
+func newRangeCursor(length int64, offsets string) *rangeCursor {
+	return &rangeCursor{length: length, random: offsets == randomRanges, next: make(map[uint]int64)}
+}
+
+// pick returns the offset and length of the next range of an object
+func (c *rangeCursor) pick(index uint, size int64, rng *rand.Rand) (int64, int64) {
+	length := c.length
+	if length > size {
+		length = size
+	}
+	if c.random {
+		return rng.Int63n(size - length + 1), length
+	}
+	offset := c.next[index]
+	if offset+length > size {
+		offset = 0
+	}
+	c.next[index] = offset + length
+	return offset, length
+}
+
+// setRange turns a read into a ranged read of the next range of its object
+func (c *rangeCursor) setRange(req *request, rng *rand.Rand) {
+	input, ok := req.input.(*s3.GetObjectInput)
+	if !ok || req.size == 0 {
+		return
+	}
+	offset, length := c.pick(req.index, req.size, rng)
+	input.Range = aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	req.size, req.offset = length, offset
+}
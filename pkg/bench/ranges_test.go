@@ -0,0 +1,101 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestRangeCursorSequential(t *testing.T) {
+	tests := []struct {
+		name   string
+		length int64
+		size   int64
+		// want holds the offset and length of the successive ranges
+		want [][2]int64
+	}{
+		{"exact", 4, 12, [][2]int64{{0, 4}, {4, 4}, {8, 4}, {0, 4}}},
+		{"wraparound", 4, 10, [][2]int64{{0, 4}, {4, 4}, {0, 4}, {4, 4}}},
+		{"whole object", 4, 4, [][2]int64{{0, 4}, {0, 4}}},
+		{"smaller object", 8, 5, [][2]int64{{0, 5}, {0, 5}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := newRangeCursor(test.length, sequentialRanges)
+			for i, want := range test.want {
+				offset, length := c.pick(1, test.size, nil)
+				if offset != want[0] || length != want[1] {
+					t.Errorf("pick() #%d = %d, %d, want %d, %d", i, offset, length, want[0], want[1])
+				}
+			}
+		})
+	}
+}
+
+func TestRangeCursorPerObject(t *testing.T) {
+	c := newRangeCursor(4, sequentialRanges)
+	c.pick(1, 12, nil)
+	if offset, _ := c.pick(2, 12, nil); offset != 0 {
+		t.Errorf("pick() of another object = %d, want 0", offset)
+	}
+	if offset, _ := c.pick(1, 12, nil); offset != 4 {
+		t.Errorf("pick() = %d, want 4", offset)
+	}
+}
+
+func TestRangeCursorRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	c := newRangeCursor(4, randomRanges)
+	seen := make(map[int64]bool)
+	for i := 0; i < 1000; i++ {
+		offset, length := c.pick(1, 10, rng)
+		if length != 4 || offset < 0 || offset+length > 10 {
+			t.Fatalf("pick() = %d, %d, want a range within 10 bytes", offset, length)
+		}
+		seen[offset] = true
+	}
+	if len(seen) != 7 {
+		t.Errorf("pick() used %d offsets, want all 7", len(seen))
+	}
+	if offset, length := c.pick(1, 3, rng); offset != 0 || length != 3 {
+		t.Errorf("pick() of a smaller object = %d, %d, want 0, 3", offset, length)
+	}
+}
+
+func TestSetRange(t *testing.T) {
+	c := newRangeCursor(4, sequentialRanges)
+	var req request
+	for i := 0; i < 2; i++ {
+		req = request{index: 1, size: 10, input: &s3.GetObjectInput{}}
+		c.setRange(&req, nil)
+	}
+	if r := aws.StringValue(req.input.(*s3.GetObjectInput).Range); r != "bytes=4-7" || req.size != 4 || req.offset != 4 {
+		t.Errorf("setRange() = %s, size %d, offset %d, want bytes=4-7, 4, 4", r, req.size, req.offset)
+	}
+	empty := request{index: 2, size: 0, input: &s3.GetObjectInput{}}
+	if c.setRange(&empty, nil); empty.input.(*s3.GetObjectInput).Range != nil {
+		t.Errorf("setRange() set a range on an empty object")
+	}
+}
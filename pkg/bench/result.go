@@ -0,0 +1,97 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Result holds the outcome of a benchmark run in a machine-readable form
+type Result struct {
+	Config     *Config           `json:"config"`
+	Parameters string            `json:"parameters"`
+	StartTime  time.Time         `json:"startTime"`
+	EndTime    time.Time         `json:"endTime"`
+	Operations []OperationResult `json:"operations"`
+	// Summary combines every stage of a multi-stage workload
+	Summary *Breakdown `json:"summary,omitempty"`
+	// Sweep holds the steps of a concurrency sweep
+	Sweep *SweepResult `json:"sweep,omitempty"`
+}
+
+// OperationResult summarizes the responses collected for one operation
+type OperationResult struct {
+	Operation   string       `json:"operation"`
+	StartTime   time.Time    `json:"startTime"`
+	EndTime     time.Time    `json:"endTime"`
+	Duration    float64      `json:"durationSeconds"`
+	Count       int          `json:"count"`
+	Bytes       int64        `json:"bytes"`
+	Errors      int          `json:"errors"`
+	Throughput  float64      `json:"throughputMBps"`
+	Percentiles []Percentile `json:"percentiles,omitempty"`
+	Intervals   []Interval   `json:"intervals,omitempty"`
+	Histogram   []Bucket     `json:"histogram,omitempty"`
+	// Time to first byte of reads
+	TTFBPercentiles []Percentile `json:"ttfbPercentiles,omitempty"`
+	TTFBHistogram   []Bucket     `json:"ttfbHistogram,omitempty"`
+	// Open-loop latency measured from the intended send time
+	CorrectedPercentiles []Percentile `json:"correctedPercentiles,omitempty"`
+	CorrectedHistogram   []Bucket     `json:"correctedHistogram,omitempty"`
+	Endpoints            []Breakdown  `json:"endpoints,omitempty"`
+	Mix                  []Breakdown  `json:"mix,omitempty"`
+	Sizes                []Breakdown  `json:"sizes,omitempty"`
+	// Responses of the warm-up, which are left out of everything else
+	Warmup       *Breakdown   `json:"warmup,omitempty"`
+	ErrorClasses []ErrorClass `json:"errorClasses,omitempty"`
+}
+
+// Breakdown summarizes a subset of the responses of an operation
+type Breakdown struct {
+	Name        string       `json:"name"`
+	Count       int          `json:"count"`
+	Bytes       int64        `json:"bytes"`
+	Errors      int          `json:"errors"`
+	Throughput  float64      `json:"throughputMBps"`
+	Percentiles []Percentile `json:"percentiles,omitempty"`
+}
+
+// Percentile is the operation time, in seconds, at the given percentile
+type Percentile struct {
+	Percentile float64 `json:"percentile"`
+	Seconds    float64 `json:"seconds"`
+}
+
+// Bucket counts the operations that took more than the previous bucket's
+// upper bound and at most UpperBound seconds
+type Bucket struct {
+	UpperBound float64 `json:"upperBoundSeconds"`
+	Count      int64   `json:"count"`
+}
+
+// WriteJSON writes the result to w as indented JSON
+func (r *Result) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
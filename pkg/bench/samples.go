@@ -0,0 +1,136 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	csvSamples   = "csv"
+	jsonlSamples = "jsonl"
+)
+
+// A sampleWriter streams every completed request to a file, flagging the
+// ones of the warm-up. Write errors are sticky and reported by close.
+type sampleWriter interface {
+	write(resp response, warmup bool)
+	close() error
+}
+
+type sample struct {
+	Start    time.Time `json:"start"`
+	Op       string    `json:"operation"`
+	Key      string    `json:"key"`
+	Endpoint string    `json:"endpoint"`
+	Client   uint      `json:"client"`
+	Duration float64   `json:"durationSeconds"`
+	Bytes    int64     `json:"bytes"`
+	Error    string    `json:"error,omitempty"`
+	Warmup   bool      `json:"warmup,omitempty"`
+}
+
+func newSample(resp response, warmup bool) sample {
+	s := sample{
+		Start:    resp.start,
+		Op:       resp.op,
+		Key:      resp.key,
+		Endpoint: resp.endpoint,
+		Client:   resp.client,
+		Duration: resp.duration.Seconds(),
+		Bytes:    resp.bytes,
+		Warmup:   warmup,
+	}
+	if resp.err != nil {
+		s.Error = resp.err.Error()
+	}
+	return s
+}
+
+func newSampleWriter(path, format string) (sampleWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create sample log: %v", err)
+	}
+	switch format {
+	case jsonlSamples:
+		buffered := bufio.NewWriter(file)
+		return &jsonlSampleWriter{file, buffered, json.NewEncoder(buffered)}, nil
+	default:
+		w := &csvSampleWriter{file, csv.NewWriter(file)}
+		w.csv.Write([]string{"start", "operation", "key", "endpoint", "client", "duration_seconds", "bytes", "error", "warmup"})
+		return w, nil
+	}
+}
+
+type csvSampleWriter struct {
+	file *os.File
+	csv  *csv.Writer
+}
+
+func (w *csvSampleWriter) write(resp response, warmup bool) {
+	s := newSample(resp, warmup)
+	w.csv.Write([]string{
+		s.Start.Format(time.RFC3339Nano),
+		s.Op,
+		s.Key,
+		s.Endpoint,
+		strconv.FormatUint(uint64(s.Client), 10),
+		strconv.FormatFloat(s.Duration, 'f', 6, 64),
+		strconv.FormatInt(s.Bytes, 10),
+		s.Error,
+		strconv.FormatBool(s.Warmup),
+	})
+}
+
+func (w *csvSampleWriter) close() error {
+	w.csv.Flush()
+	err := w.csv.Error()
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+type jsonlSampleWriter struct {
+	file     *os.File
+	buffered *bufio.Writer
+	encoder  *json.Encoder
+}
+
+func (w *jsonlSampleWriter) write(resp response, warmup bool) {
+	// bufio.Writer keeps the first error, which close returns
+	w.encoder.Encode(newSample(resp, warmup))
+}
+
+func (w *jsonlSampleWriter) close() error {
+	err := w.buffered.Flush()
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
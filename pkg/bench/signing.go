@@ -0,0 +1,245 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	awsrequest "github.com/aws/aws-sdk-go/aws/request"
+)
+
+const (
+	unsignedPayload  = "unsigned"
+	signedPayload    = "signed"
+	streamingPayload = "streaming"
+	// streamingChunkSize is the size of the chunks of streaming uploads
+	streamingChunkSize = 64 * 1024
+	emptySHA256        = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// checksumHeaders maps the checksum modes to the header they are sent in
+var checksumHeaders = map[string]string{
+	"md5":    "Content-MD5",
+	"crc32":  "X-Amz-Checksum-Crc32",
+	"crc32c": "X-Amz-Checksum-Crc32c",
+	"sha1":   "X-Amz-Checksum-Sha1",
+	"sha256": "X-Amz-Checksum-Sha256",
+}
+
+func newChecksum(name string) hash.Hash {
+	switch name {
+	case "md5":
+		return md5.New()
+	case "crc32":
+		return crc32.NewIEEE()
+	case "crc32c":
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case "sha1":
+		return sha1.New()
+	}
+	return sha256.New()
+}
+
+// payloadOption applies the payload signing and checksum modes to the
+// requests that upload object content
+func (conf *Config) payloadOption(req *awsrequest.Request) {
+	if req.Operation == nil || (req.Operation.Name != "PutObject" && req.Operation.Name != "UploadPart") {
+		return
+	}
+	// The SDK only hashes the body itself if X-Amz-Content-Sha256 isn't set
+	// by the time its Build handlers run
+	switch conf.PayloadSigning {
+	case unsignedPayload:
+		req.HTTPRequest.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+	case streamingPayload:
+		req.HTTPRequest.Header.Set("X-Amz-Content-Sha256", "STREAMING-AWS4-HMAC-SHA256-PAYLOAD")
+		req.Handlers.Build.PushBack(buildStreaming)
+		req.Handlers.Send.PushFront(conf.signChunks)
+	}
+	// Signed payloads are hashed by the SDK when it signs the request, and
+	// Content-MD5 is only computed by the SDK in the md5 mode, see Mark
+	if conf.Checksum != "" && conf.Checksum != "md5" {
+		req.Handlers.Build.PushBack(conf.buildChecksum)
+	}
+}
+
+// buildChecksum sends the checksum of the body in the header of its mode
+func (conf *Config) buildChecksum(req *awsrequest.Request) {
+	checksum := newChecksum(conf.Checksum)
+	if err := hashBody(req.Body, checksum); err != nil {
+		req.Error = fmt.Errorf("Unable to compute the %s checksum: %v", conf.Checksum, err)
+		return
+	}
+	req.HTTPRequest.Header.Set(checksumHeaders[conf.Checksum], base64.StdEncoding.EncodeToString(checksum.Sum(nil)))
+}
+
+// hashBody writes the rest of body to h, leaving body where it was
+func hashBody(body io.ReadSeeker, h hash.Hash) error {
+	if body == nil {
+		return nil
+	}
+	start, err := body.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(h, body); err != nil {
+		return err
+	}
+	_, err = body.Seek(start, io.SeekStart)
+	return err
+}
+
+// buildStreaming declares the body as aws-chunked before the request is
+// signed, so that the signature covers the length of the chunked body
+func buildStreaming(req *awsrequest.Request) {
+	var size int64
+	if req.Body != nil {
+		start, err := req.Body.Seek(0, io.SeekCurrent)
+		if err == nil {
+			size, err = req.Body.Seek(0, io.SeekEnd)
+		}
+		if err == nil {
+			_, err = req.Body.Seek(start, io.SeekStart)
+		}
+		if err != nil {
+			req.Error = fmt.Errorf("Unable to measure the body: %v", err)
+			return
+		}
+		size -= start
+	}
+	header := req.HTTPRequest.Header
+	header.Set("Content-Encoding", "aws-chunked")
+	header.Set("X-Amz-Decoded-Content-Length", strconv.FormatInt(size, 10))
+	header.Set("Content-Length", strconv.FormatInt(chunkedLength(size), 10))
+}
+
+// chunkedLength returns the length of size bytes once encoded in signed chunks
+func chunkedLength(size int64) int64 {
+	chunk := func(n int64) int64 {
+		return int64(len(strconv.FormatInt(n, 16))+len(";chunk-signature=")+64+4) + n
+	}
+	length := size / streamingChunkSize * chunk(streamingChunkSize)
+	if rest := size % streamingChunkSize; rest > 0 {
+		length += chunk(rest)
+	}
+	return length + chunk(0)
+}
+
+// signChunks replaces the body of a signed request with signed chunks,
+// each chained to the signature of the request
+func (conf *Config) signChunks(req *awsrequest.Request) {
+	header := req.HTTPRequest.Header
+	auth := header.Get("Authorization")
+	i := strings.Index(auth, "Signature=")
+	timestamp := header.Get("X-Amz-Date")
+	if i < 0 || len(timestamp) < 8 {
+		req.Error = fmt.Errorf("Unable to sign chunks of an unsigned request")
+		return
+	}
+	date := timestamp[:8]
+	body := req.HTTPRequest.Body
+	if body == nil {
+		body = http.NoBody
+	}
+	req.HTTPRequest.Body = &chunkSigner{
+		body:      body,
+		key:       signingKey(conf.SecretKey, date, conf.Region),
+		timestamp: timestamp,
+		scope:     fmt.Sprintf("%s/%s/s3/aws4_request", date, conf.Region),
+		previous:  auth[i+len("Signature="):],
+		chunk:     make([]byte, streamingChunkSize),
+	}
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func signingKey(secret, date, region string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	return hmacSHA256(key, "aws4_request")
+}
+
+// chunkSigner encodes a body in aws-chunked chunks, each signed with the
+// signature of the previous one
+type chunkSigner struct {
+	body      io.ReadCloser
+	key       []byte
+	timestamp string
+	scope     string
+	previous  string
+	chunk     []byte
+	encoded   bytes.Buffer
+	done      bool
+}
+
+func (c *chunkSigner) Read(b []byte) (int, error) {
+	for c.encoded.Len() == 0 {
+		if c.done {
+			return 0, io.EOF
+		}
+		n, err := io.ReadFull(c.body, c.chunk)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return 0, err
+		}
+		c.sign(c.chunk[:n])
+		// The last chunk is empty
+		c.done = n == 0
+	}
+	return c.encoded.Read(b)
+}
+
+func (c *chunkSigner) sign(data []byte) {
+	digest := sha256.Sum256(data)
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256-PAYLOAD",
+		c.timestamp,
+		c.scope,
+		c.previous,
+		emptySHA256,
+		hex.EncodeToString(digest[:]),
+	}, "\n")
+	c.previous = hex.EncodeToString(hmacSHA256(c.key, stringToSign))
+	fmt.Fprintf(&c.encoded, "%x;chunk-signature=%s\r\n", len(data), c.previous)
+	c.encoded.Write(data)
+	c.encoded.WriteString("\r\n")
+}
+
+func (c *chunkSigner) Close() error {
+	return c.body.Close()
+}
@@ -0,0 +1,162 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestChunkedLength(t *testing.T) {
+	tests := []struct {
+		size int64
+		want int64
+	}{
+		// The example of the AWS documentation of streaming uploads
+		{66560, 66824},
+		// Every chunk has its size in hex, a 81 byte signature and two CRLF
+		{0, 86},
+		{1, 87 + 86},
+		{streamingChunkSize, 90 + streamingChunkSize + 86},
+		{2*streamingChunkSize + 16, 2*(90+streamingChunkSize) + 87 + 16 + 86},
+	}
+	for _, test := range tests {
+		if length := chunkedLength(test.size); length != test.want {
+			t.Errorf("chunkedLength(%d) = %d, want %d", test.size, length, test.want)
+		}
+	}
+}
+
+// TestChunkSigner signs the example of the AWS documentation of streaming
+// uploads, 66560 bytes of 'a' sent in two chunks and a final empty one
+func TestChunkSigner(t *testing.T) {
+	body := bytes.Repeat([]byte{'a'}, 66560)
+	signer := &chunkSigner{
+		body:      ioutil.NopCloser(bytes.NewReader(body)),
+		key:       signingKey("wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY", "20130524", "us-east-1"),
+		timestamp: "20130524T000000Z",
+		scope:     "20130524/us-east-1/s3/aws4_request",
+		previous:  "4f232c4386841ef735655705268965c44a0e4690baa4adea153f7db9fa80a0a9",
+		chunk:     make([]byte, streamingChunkSize),
+	}
+	encoded, err := ioutil.ReadAll(signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(encoded)) != chunkedLength(int64(len(body))) {
+		t.Errorf("encoded %d bytes, want %d", len(encoded), chunkedLength(int64(len(body))))
+	}
+	chunks := []struct {
+		size      int
+		signature string
+	}{
+		{65536, "ad80c730a21e5b8d04586a2213dd63b9a0e99e0e2307b0ade35a65485a288648"},
+		{1024, "0055627c9e194cb4542bae2aa5492e3c1575bbb81b612b7d234b86a503ef5497"},
+		{0, "b6c6ea8a5354eaf15b3cb7646744f4275b71ea724fed81ceb9323e279d449df9"},
+	}
+	rest := string(encoded)
+	for i, chunk := range chunks {
+		header := strings.SplitN(rest, "\r\n", 2)
+		want := fmt.Sprintf("%x;chunk-signature=%s", chunk.size, chunk.signature)
+		if header[0] != want {
+			t.Fatalf("chunk %d starts with %q, want %q", i, header[0], want)
+		}
+		data := header[1][:chunk.size]
+		if data != string(body[:chunk.size]) || header[1][chunk.size:chunk.size+2] != "\r\n" {
+			t.Fatalf("chunk %d doesn't hold %d bytes of the body", i, chunk.size)
+		}
+		rest = header[1][chunk.size+2:]
+	}
+	if rest != "" {
+		t.Errorf("%d bytes follow the last chunk", len(rest))
+	}
+}
+
+func TestPayloadOption(t *testing.T) {
+	tests := []struct {
+		signing  string
+		checksum string
+		sha256   string
+		md5      bool
+	}{
+		{unsignedPayload, "", "UNSIGNED-PAYLOAD", false},
+		{unsignedPayload, "md5", "UNSIGNED-PAYLOAD", true},
+		{signedPayload, "", "", false},
+		{signedPayload, "crc32", "", false},
+		{streamingPayload, "", "STREAMING-AWS4-HMAC-SHA256-PAYLOAD", false},
+		{streamingPayload, "md5", "STREAMING-AWS4-HMAC-SHA256-PAYLOAD", true},
+		{streamingPayload, "crc32", "STREAMING-AWS4-HMAC-SHA256-PAYLOAD", false},
+	}
+	for _, test := range tests {
+		t.Run(test.signing+"/"+test.checksum, func(t *testing.T) {
+			var header http.Header
+			var body []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				header = req.Header
+				body, _ = ioutil.ReadAll(req.Body)
+			}))
+			defer server.Close()
+			conf := &Config{SecretKey: "secret", Region: "us-east-1", PayloadSigning: test.signing, Checksum: test.checksum}
+			client := s3.New(session.New(&aws.Config{
+				Credentials:                   credentials.NewStaticCredentials("access", "secret", ""),
+				Endpoint:                      aws.String(server.URL),
+				Region:                        aws.String("us-east-1"),
+				S3ForcePathStyle:              aws.Bool(true),
+				S3DisableContentMD5Validation: aws.Bool(test.checksum != "md5"),
+			}))
+			content := newPayload(DataPattern{randomData, 1, 1}, 1, "key", 100000)
+			req, _ := client.PutObjectRequest(&s3.PutObjectInput{Bucket: aws.String("bucket"), Key: aws.String("key"), Body: content})
+			req.ApplyOptions(conf.payloadOption)
+			if err := req.Send(); err != nil {
+				t.Fatal(err)
+			}
+			if sha256 := header.Get("X-Amz-Content-Sha256"); (test.sha256 == "" && len(sha256) != 64) || (test.sha256 != "" && sha256 != test.sha256) {
+				t.Errorf("X-Amz-Content-Sha256 = %q, want %q", sha256, test.sha256)
+			}
+			if md5 := header.Get("Content-MD5"); (md5 != "") != test.md5 {
+				t.Errorf("Content-MD5 = %q", md5)
+			}
+			if crc := header.Get("X-Amz-Checksum-Crc32"); (crc != "") != (test.checksum == "crc32") {
+				t.Errorf("X-Amz-Checksum-Crc32 = %q", crc)
+			}
+			if test.signing == streamingPayload {
+				if int64(len(body)) != chunkedLength(content.size) {
+					t.Errorf("sent %d bytes, want %d", len(body), chunkedLength(content.size))
+				}
+				return
+			}
+			expected, _ := ioutil.ReadAll(newPayload(DataPattern{randomData, 1, 1}, 1, "key", 100000))
+			if !bytes.Equal(body, expected) {
+				t.Errorf("sent a body that differs from the payload")
+			}
+		})
+	}
+}
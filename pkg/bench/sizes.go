@@ -0,0 +1,314 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sizeDistribution draws the size of every object of a run
+type sizeDistribution interface {
+	sample(rng *rand.Rand) int64
+	String() string
+}
+
+// parseSizeDistribution parses one of
+//
+//	fixed:4KiB                a single size
+//	fixed:4KiB=70,1MiB=30     sizes with their weights
+//	uniform:1KiB-1MiB         sizes uniformly distributed in a range
+//	lognormal:64KiB,32KiB     log-normal sizes with the given mean and stddev
+//	histogram:sizes.csv       size,weight lines read from a file
+func parseSizeDistribution(spec string) (sizeDistribution, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("objectSizes(%s) needs to be of the form type:parameters", spec)
+	}
+	kind, params := strings.ToLower(parts[0]), parts[1]
+	switch kind {
+	case "fixed":
+		if !strings.Contains(params, "=") {
+			size, err := parseSize(params)
+			if err != nil {
+				return nil, err
+			}
+			return weightedSizes{spec, []int64{size}, []float64{1}, 1}, nil
+		}
+		return parseWeightedSizes(spec, strings.Split(params, ","), "=")
+	case "uniform":
+		bounds := strings.SplitN(params, "-", 2)
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("objectSizes(%s) needs a range of the form min-max", spec)
+		}
+		min, err := parseSize(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+		max, err := parseSize(bounds[1])
+		if err != nil {
+			return nil, err
+		}
+		if min > max {
+			return nil, fmt.Errorf("objectSizes(%s) needs min to be less than max", spec)
+		}
+		return uniformSizes{min, max}, nil
+	case "lognormal":
+		values := strings.SplitN(params, ",", 2)
+		if len(values) != 2 {
+			return nil, fmt.Errorf("objectSizes(%s) needs a mean and a standard deviation", spec)
+		}
+		mean, err := parseSize(values[0])
+		if err != nil {
+			return nil, err
+		}
+		stddev, err := parseSize(values[1])
+		if err != nil {
+			return nil, err
+		}
+		// parseSize already rejects a negative stddev
+		if mean <= 0 {
+			return nil, fmt.Errorf("objectSizes(%s) needs a mean greater than 0", spec)
+		}
+		return newLognormalSizes(mean, stddev), nil
+	case "histogram":
+		file, err := os.Open(params)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		lines, err := readLines(file)
+		if err != nil {
+			return nil, err
+		}
+		return parseWeightedSizes(spec, lines, ",")
+	}
+	return nil, fmt.Errorf("objectSizes type %q needs to be one of fixed, uniform, lognormal or histogram", kind)
+}
+
+// parseSize parses a number of bytes with an optional binary unit suffix
+// such as 512, 4KB, 4KiB or 1G
+func parseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	upper := strings.ToUpper(s)
+	multiplier := int64(1)
+	for i, unit := range []string{"K", "M", "G", "T"} {
+		for _, suffix := range []string{unit + "IB", unit + "B", unit} {
+			if strings.HasSuffix(upper, suffix) {
+				upper = strings.TrimSuffix(upper, suffix)
+				multiplier = 1 << (10 * uint(i+1))
+				break
+			}
+		}
+		if multiplier > 1 {
+			break
+		}
+	}
+	upper = strings.TrimSuffix(strings.TrimSpace(upper), "B")
+	value, err := strconv.ParseInt(strings.TrimSpace(upper), 10, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("Invalid size %q", s)
+	}
+	return value * multiplier, nil
+}
+
+// formatSize renders a number of bytes with the largest exact binary unit
+func formatSize(size int64) string {
+	for _, unit := range []struct {
+		name string
+		size int64
+	}{{"TiB", 1 << 40}, {"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10}} {
+		if size >= unit.size && size%unit.size == 0 {
+			return fmt.Sprintf("%d%s", size/unit.size, unit.name)
+		}
+	}
+	return fmt.Sprintf("%dB", size)
+}
+
+func readLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// weightedSizes draws sizes from a list of sizes with their weights
+type weightedSizes struct {
+	spec    string
+	sizes   []int64
+	weights []float64
+	total   float64
+}
+
+func parseWeightedSizes(spec string, entries []string, separator string) (weightedSizes, error) {
+	w := weightedSizes{spec: spec}
+	for _, entry := range entries {
+		parts := strings.SplitN(entry, separator, 2)
+		if len(parts) != 2 {
+			return w, fmt.Errorf("objectSizes entry %q needs to be of the form size%sweight", entry, separator)
+		}
+		size, err := parseSize(parts[0])
+		if err != nil {
+			return w, err
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil || weight < 0 {
+			return w, fmt.Errorf("objectSizes weight %q needs to be a positive number", parts[1])
+		}
+		w.sizes = append(w.sizes, size)
+		w.weights = append(w.weights, weight)
+		w.total += weight
+	}
+	if w.total == 0 {
+		return w, fmt.Errorf("objectSizes(%s) needs at least one size with a positive weight", spec)
+	}
+	return w, nil
+}
+
+func (w weightedSizes) sample(rng *rand.Rand) int64 {
+	n := rng.Float64() * w.total
+	for i, weight := range w.weights {
+		if n < weight {
+			return w.sizes[i]
+		}
+		n -= weight
+	}
+	return w.sizes[len(w.sizes)-1]
+}
+
+func (w weightedSizes) String() string {
+	return w.spec
+}
+
+type uniformSizes struct {
+	min int64
+	max int64
+}
+
+func (u uniformSizes) sample(rng *rand.Rand) int64 {
+	return u.min + rng.Int63n(u.max-u.min+1)
+}
+
+func (u uniformSizes) String() string {
+	return fmt.Sprintf("uniform:%s-%s", formatSize(u.min), formatSize(u.max))
+}
+
+// lognormalSizes draws sizes with the given mean and standard deviation.
+// Sizes are capped four standard deviations above the mean of the
+// underlying normal distribution, which keeps 99.997% of the samples.
+type lognormalSizes struct {
+	mean   int64
+	stddev int64
+	mu     float64
+	sigma  float64
+	limit  int64
+}
+
+func newLognormalSizes(mean, stddev int64) lognormalSizes {
+	variance := math.Log(1 + math.Pow(float64(stddev)/float64(mean), 2))
+	mu := math.Log(float64(mean)) - variance/2
+	sigma := math.Sqrt(variance)
+	return lognormalSizes{mean, stddev, mu, sigma, int64(math.Exp(mu + 4*sigma))}
+}
+
+func (l lognormalSizes) sample(rng *rand.Rand) int64 {
+	size := int64(math.Exp(l.mu + l.sigma*rng.NormFloat64()))
+	if size > l.limit {
+		size = l.limit
+	}
+	return size
+}
+
+func (l lognormalSizes) String() string {
+	return fmt.Sprintf("lognormal:%s,%s", formatSize(l.mean), formatSize(l.stddev))
+}
+
+// drawSizes returns the size of every key. The sizes only depend on the
+// seed and the distribution, so that a later run reading the objects
+// expects the sizes they were written with.
+func drawSizes(dist sizeDistribution, seed int64, count uint) []int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(dist.String()))
+	rng := rand.New(rand.NewSource(int64(hash.Sum64()) ^ seed))
+	sizes := make([]int64, count)
+	for i := range sizes {
+		sizes[i] = dist.sample(rng)
+	}
+	return sizes
+}
+
+// sizeBucket returns the smallest power of two holding size, which is the
+// bucket its operations are reported under
+func sizeBucket(size int64) int64 {
+	bucket := int64(1)
+	for bucket < size {
+		bucket <<= 1
+	}
+	return bucket
+}
+
+// sizeBuckets holds the stats of every size bucket of a report
+type sizeBuckets map[int64]*stats
+
+func (b sizeBuckets) add(resp response, precision int) {
+	bucket := sizeBucket(resp.size)
+	s, ok := b[bucket]
+	if !ok {
+		s = newStats(precision)
+		b[bucket] = s
+	}
+	s.add(resp)
+}
+
+func (b sizeBuckets) sorted() []int64 {
+	buckets := make([]int64, 0, len(b))
+	for bucket := range b {
+		buckets = append(buckets, bucket)
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+	return buckets
+}
+
+func (b sizeBuckets) label(bucket int64) string {
+	return "<=" + formatSize(bucket)
+}
+
+func (b sizeBuckets) breakdowns(duration time.Duration) []Breakdown {
+	var result []Breakdown
+	for _, bucket := range b.sorted() {
+		result = append(result, b[bucket].breakdown(b.label(bucket), duration))
+	}
+	return result
+}
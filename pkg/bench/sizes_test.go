@@ -0,0 +1,147 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		size  string
+		want  int64
+		error bool
+	}{
+		{"512", 512, false},
+		{"512B", 512, false},
+		{"4K", 4 << 10, false},
+		{"4KB", 4 << 10, false},
+		{"4KiB", 4 << 10, false},
+		{" 16kib ", 16 << 10, false},
+		{"1MiB", 1 << 20, false},
+		{"2G", 2 << 30, false},
+		{"1TiB", 1 << 40, false},
+		{"0", 0, false},
+		{"-1", 0, true},
+		{"1.5MiB", 0, true},
+		{"KiB", 0, true},
+		{"", 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.size, func(t *testing.T) {
+			size, err := parseSize(test.size)
+			if test.error {
+				if err == nil {
+					t.Errorf("parseSize(%q) = %d, want an error", test.size, size)
+				}
+				return
+			}
+			if err != nil || size != test.want {
+				t.Errorf("parseSize(%q) = %d, %v, want %d", test.size, size, err, test.want)
+			}
+		})
+	}
+}
+
+func TestParseSizeDistribution(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sizes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	histogram := filepath.Join(dir, "sizes.csv")
+	if err := ioutil.WriteFile(histogram, []byte("# size,weight\n4KiB,3\n\n1MiB,1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		spec  string
+		min   int64
+		max   int64
+		error bool
+	}{
+		{"fixed:4KiB", 4 << 10, 4 << 10, false},
+		{"fixed:4KiB=70,1MiB=30", 4 << 10, 1 << 20, false},
+		{"FIXED:1KiB=1,2KiB=0", 1 << 10, 1 << 10, false},
+		{"uniform:1KiB-64KiB", 1 << 10, 64 << 10, false},
+		{"uniform:4KiB-4KiB", 4 << 10, 4 << 10, false},
+		{"lognormal:64KiB,16KiB", 1, 64 << 20, false},
+		{"lognormal:64KiB,0", 64<<10 - 1, 64 << 10, false},
+		{"histogram:" + histogram, 4 << 10, 1 << 20, false},
+		{"fixed", 0, 0, true},
+		{"fixed:", 0, 0, true},
+		{"fixed:4KiB=0", 0, 0, true},
+		{"fixed:4KiB=-1,1MiB=1", 0, 0, true},
+		{"fixed:4KiB,1MiB=1", 0, 0, true},
+		{"uniform:64KiB-1KiB", 0, 0, true},
+		{"uniform:1KiB", 0, 0, true},
+		{"lognormal:0,16KiB", 0, 0, true},
+		{"lognormal:64KiB,-1", 0, 0, true},
+		{"lognormal:64KiB", 0, 0, true},
+		{"histogram:" + filepath.Join(dir, "missing.csv"), 0, 0, true},
+		{"pareto:1KiB", 0, 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			dist, err := parseSizeDistribution(test.spec)
+			if test.error {
+				if err == nil {
+					t.Errorf("parseSizeDistribution(%q) = %s, want an error", test.spec, dist)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSizeDistribution(%q) failed: %v", test.spec, err)
+			}
+			rng := rand.New(rand.NewSource(1))
+			for i := 0; i < 1000; i++ {
+				if size := dist.sample(rng); size < test.min || size > test.max {
+					t.Fatalf("%s drew %d, want a size in [%d, %d]", dist, size, test.min, test.max)
+				}
+			}
+		})
+	}
+}
+
+func TestDrawSizes(t *testing.T) {
+	dist, _ := parseSizeDistribution("uniform:1KiB-64KiB")
+	sizes := drawSizes(dist, 7, 100)
+	// A later run needs the same sizes, even with fewer keys
+	again := drawSizes(dist, 7, 50)
+	for i := range again {
+		if again[i] != sizes[i] {
+			t.Fatalf("drawSizes() drew %d for key %d, then %d", sizes[i], i, again[i])
+		}
+	}
+	other := drawSizes(dist, 8, 100)
+	same := 0
+	for i := range sizes {
+		if other[i] == sizes[i] {
+			same++
+		}
+	}
+	if same == len(sizes) {
+		t.Errorf("drawSizes() drew the same sizes with another seed")
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+const (
+	prepareStage = "prepare"
+	sleepStage   = "sleep"
+	cleanupStage = "cleanup"
+)
+
+// stageOps maps the stage types that run operations to their operations
+var stageOps = map[string]string{
+	prepareStage: writeOp,
+	"write":      writeOp,
+	"read":       readOp,
+	"head":       headOp,
+	"list":       listOp,
+	"copy":       copyOp,
+	"mixed":      mixedOp,
+	"delete":     deleteOp,
+}
+
+// Stage is one step of a multi-stage workload. Parameters left unset fall
+// back to the ones of the run.
+type Stage struct {
+	Name        string        `json:"name,omitempty" mapstructure:"name"`
+	Type        string        `json:"type" mapstructure:"type"`
+	Clients     uint          `json:"numClients,omitempty" mapstructure:"numClients"`
+	ObjectCount uint          `json:"numSamples,omitempty" mapstructure:"numSamples"`
+	ObjectSize  int64         `json:"objectSize,omitempty" mapstructure:"objectSize"`
+	ObjectSizes string        `json:"objectSizes,omitempty" mapstructure:"objectSizes"`
+	Duration    time.Duration `json:"duration,omitempty" mapstructure:"duration"`
+	Mix         string        `json:"mix,omitempty" mapstructure:"mix"`
+	Rate        float64       `json:"rate,omitempty" mapstructure:"rate"`
+}
+
+// label names the reports of the stage, which need to be unique for
+// results to be compared
+func (s Stage) label(i int) string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return fmt.Sprintf("%s-%d", stageOps[s.Type], i+1)
+}
+
+// config returns the run configuration with the stage's parameters applied
+func (s Stage) config(base *Config) (*Config, error) {
+	conf := *base
+	conf.Stages = nil
+	if s.Clients > 0 {
+		conf.Clients = s.Clients
+	}
+	if s.ObjectCount > 0 {
+		conf.ObjectCount = s.ObjectCount
+	}
+	if s.ObjectSize > 0 {
+		conf.ObjectSize = s.ObjectSize
+		conf.ObjectSizes = ""
+	}
+	if s.ObjectSizes != "" {
+		conf.ObjectSizes = s.ObjectSizes
+	}
+	if s.Duration > 0 {
+		conf.Duration = s.Duration
+	}
+	if s.Mix != "" {
+		conf.Mix = s.Mix
+	}
+	if s.Rate > 0 {
+		conf.Rate = s.Rate
+	}
+	if err := conf.validate(); err != nil {
+		return nil, err
+	}
+	return &conf, nil
+}
+
+func validateStages(stages []Stage) error {
+	for i, s := range stages {
+		s.Type = strings.ToLower(s.Type)
+		stages[i].Type = s.Type
+		if _, ok := stageOps[s.Type]; !ok && s.Type != sleepStage && s.Type != cleanupStage {
+			return fmt.Errorf("stage %d type(%s) needs to be one of prepare, write, read, head, list, copy, mixed, delete, sleep or cleanup", i+1, s.Type)
+		}
+		if s.Type == sleepStage && s.Duration <= 0 {
+			return fmt.Errorf("stage %d needs a duration to sleep for", i+1)
+		}
+	}
+	return nil
+}
+
+// runStages runs every stage of the workload in order and returns their
+// reports. Prepare stages write the objects the following stages work on
+// without being reported.
+func (r *Runner) runStages(awsCfg *aws.Config) ([]report, error) {
+	base := r.conf
+	defer func() { r.conf = base }()
+	total := newStats(base.Precision)
+	defer func() { r.total = total }()
+	confs := make([]*Config, len(base.Stages))
+	for i, stage := range base.Stages {
+		conf, err := stage.config(base)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to run stage %d: %v", i+1, err)
+		}
+		if stageOps[stage.Type] == mixedOp && conf.Mix == "" {
+			return nil, fmt.Errorf("Unable to run stage %d: mixed stages need a mix", i+1)
+		}
+		confs[i] = conf
+	}
+	var reports []report
+	copied := false
+	// written is the highest number of keys the stages may have written, and
+	// objects are read with the sizes of the last stage writing them
+	written := uint(0)
+	var writtenSize *Config
+	for i, stage := range base.Stages {
+		conf := confs[i]
+		r.conf = conf
+		fmt.Fprintf(r.out, "Stage %d: %s\n", i+1, stage.Type)
+		switch stage.Type {
+		case sleepStage:
+			time.Sleep(stage.Duration)
+			continue
+		case cleanupStage:
+			conf.Cleanup = true
+			conf.Copy = copied
+			if stage.ObjectCount == 0 && written > 0 {
+				conf.ObjectCount = written
+			}
+			r.cleanup(awsCfg)
+			continue
+		}
+		op := stageOps[stage.Type]
+		r.mix = nil
+		if op == mixedOp {
+			mix, err := parseMix(conf.Mix)
+			if err != nil {
+				return nil, err
+			}
+			r.mix = mix
+		}
+		copied = copied || op == copyOp || r.copies()
+		if op == writeOp {
+			r.keys = conf.ObjectCount
+			writtenSize = conf
+		} else if writtenSize != nil && stage.ObjectSize == 0 && stage.ObjectSizes == "" {
+			conf.ObjectSize, conf.ObjectSizes = writtenSize.ObjectSize, writtenSize.ObjectSizes
+		}
+		if (op == writeOp || op == mixedOp) && conf.ObjectCount > written {
+			written = conf.ObjectCount
+		}
+		// Sizes only depend on the seed, so stages reading objects written
+		// by an earlier run expect the sizes they were written with
+		if err := r.loadSizes(); err != nil {
+			return nil, err
+		}
+		r.total = total
+		if stage.Type == prepareStage {
+			r.total = nil
+		}
+		report := r.runClients(awsCfg, op)
+		if stage.Type == prepareStage {
+			continue
+		}
+		report.Operation = stage.label(i)
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// runClients runs op with a fresh set of clients, so that the number of
+// clients can change from one run to the next
+func (r *Runner) runClients(awsCfg *aws.Config, op string) report {
+	r.requests = make(chan request)
+	r.prepare(awsCfg)
+	defer close(r.requests)
+	return r.run(op)
+}
@@ -0,0 +1,186 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package bench
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStageLabel(t *testing.T) {
+	tests := []struct {
+		stage Stage
+		index int
+		want  string
+	}{
+		{Stage{Type: "read"}, 0, "Read-1"},
+		{Stage{Type: prepareStage}, 2, "Write-3"},
+		{Stage{Type: "mixed", Name: "steady"}, 1, "steady"},
+	}
+	for _, test := range tests {
+		if got := test.stage.label(test.index); got != test.want {
+			t.Errorf("%+v.label(%d) = %s, want %s", test.stage, test.index, got, test.want)
+		}
+	}
+}
+
+func TestStageConfig(t *testing.T) {
+	base := &Config{
+		Endpoint:    "http://localhost",
+		Clients:     2,
+		ObjectCount: 10,
+		ObjectSize:  1024,
+		ObjectSizes: "uniform:1KiB-4KiB",
+		Duration:    time.Minute,
+		Mix:         "read:1",
+		Stages:      []Stage{{Type: "read"}},
+	}
+	tests := []struct {
+		name  string
+		stage Stage
+		check func(*Config) bool
+	}{
+		{"inherited", Stage{Type: "read"}, func(c *Config) bool {
+			return c.Clients == 2 && c.ObjectCount == 10 && c.ObjectSizes == base.ObjectSizes && c.Duration == time.Minute
+		}},
+		{"overridden", Stage{Type: "mixed", Clients: 4, ObjectCount: 20, Duration: time.Second, Mix: "write:1", Rate: 5}, func(c *Config) bool {
+			return c.Clients == 4 && c.ObjectCount == 20 && c.Duration == time.Second && c.Mix == "write:1" && c.Rate == 5
+		}},
+		{"fixed size", Stage{Type: "write", ObjectSize: 64}, func(c *Config) bool {
+			return c.ObjectSize == 64 && c.ObjectSizes == ""
+		}},
+		{"size distribution", Stage{Type: "write", ObjectSizes: "fixed:2KiB"}, func(c *Config) bool {
+			return c.ObjectSize == 1024 && c.ObjectSizes == "fixed:2KiB"
+		}},
+	}
+	for _, test := range tests {
+		conf, err := test.stage.config(base)
+		if err != nil {
+			t.Errorf("%s: config() returned %v", test.name, err)
+			continue
+		}
+		if conf.Stages != nil {
+			t.Errorf("%s: config() kept the stages of the run", test.name)
+		}
+		if !test.check(conf) {
+			t.Errorf("%s: config() = %+v", test.name, conf)
+		}
+	}
+	if base.Clients != 2 || base.ObjectCount != 10 || len(base.Stages) != 1 {
+		t.Errorf("config() changed the run configuration to %+v", base)
+	}
+	if _, err := (Stage{Type: "read", Clients: 11}).config(base); err == nil {
+		t.Errorf("config() accepted more clients than samples")
+	}
+}
+
+func TestValidateStages(t *testing.T) {
+	tests := []struct {
+		name   string
+		stages []Stage
+		valid  bool
+	}{
+		{"none", nil, true},
+		{"every type", []Stage{
+			{Type: "Prepare"}, {Type: "write"}, {Type: "read"}, {Type: "head"}, {Type: "list"},
+			{Type: "copy"}, {Type: "mixed"}, {Type: "delete"}, {Type: "sleep", Duration: time.Second}, {Type: "cleanup"},
+		}, true},
+		{"unknown type", []Stage{{Type: "read"}, {Type: "scan"}}, false},
+		{"sleep without duration", []Stage{{Type: "sleep"}}, false},
+	}
+	for _, test := range tests {
+		err := validateStages(test.stages)
+		if (err == nil) != test.valid {
+			t.Errorf("%s: validateStages() returned %v", test.name, err)
+		}
+	}
+	stages := []Stage{{Type: "MIXED"}}
+	if err := validateStages(stages); err != nil || stages[0].Type != "mixed" {
+		t.Errorf("validateStages() left the type %s, returned %v", stages[0].Type, err)
+	}
+}
+
+func TestRunStagesCleanup(t *testing.T) {
+	written := map[string]bool{}
+	var deleted []string
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		key := strings.TrimPrefix(req.URL.Path, "/bucket/")
+		switch req.Method {
+		case http.MethodPut:
+			ioutil.ReadAll(req.Body)
+			written[key] = true
+		case http.MethodGet:
+			w.Write(make([]byte, 16))
+		case http.MethodPost:
+			var body struct {
+				Objects []struct{ Key string } `xml:"Object"`
+			}
+			xml.NewDecoder(req.Body).Decode(&body)
+			for _, object := range body.Objects {
+				deleted = append(deleted, object.Key)
+			}
+			w.Write([]byte("<DeleteResult></DeleteResult>"))
+		}
+	}))
+	defer server.Close()
+	conf := &Config{
+		Endpoint:         server.URL,
+		Bucket:           "bucket",
+		ObjectNamePrefix: "object",
+		Clients:          1,
+		ObjectCount:      2,
+		ObjectSize:       16,
+		Stages:           []Stage{{Type: prepareStage, ObjectCount: 8}, {Type: "read"}, {Type: cleanupStage}},
+	}
+	if err := conf.validate(); err != nil {
+		t.Fatal(err)
+	}
+	r := &Runner{
+		conf:      conf,
+		responses: make(chan response),
+		endpoints: []string{server.URL},
+		keys:      conf.ObjectCount,
+		out:       ioutil.Discard,
+	}
+	reports, err := r.runStages(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 || reports[0].Operation != "Read-2" {
+		t.Errorf("runStages() reported %+v, want the read stage only", reports)
+	}
+	if len(written) != 8 || len(deleted) != 8 {
+		t.Errorf("runStages() wrote %d objects and cleaned up %d, want 8", len(written), len(deleted))
+	}
+	for _, key := range deleted {
+		if !written[key] {
+			t.Errorf("runStages() cleaned up %s, which wasn't written", key)
+		}
+	}
+}
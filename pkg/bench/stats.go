@@ -0,0 +1,101 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// stats aggregates the responses of a subset of the operations of a report,
+// such as the ones served by a single endpoint
+type stats struct {
+	bytes   int64
+	errors  int
+	latency *latency
+}
+
+func newStats(precision int) *stats {
+	return &stats{latency: newLatency(precision)}
+}
+
+func (s *stats) add(resp response) {
+	if resp.err != nil {
+		s.errors++
+		return
+	}
+	s.bytes += resp.bytes
+	s.latency.record(resp.duration)
+}
+
+func (s *stats) breakdown(name string, duration time.Duration) Breakdown {
+	b := Breakdown{
+		Name:       name,
+		Count:      int(s.latency.count()),
+		Bytes:      s.bytes,
+		Errors:     s.errors,
+		Throughput: (float64(s.bytes) / (1024 * 1024)) / duration.Seconds(),
+	}
+	if b.Count > 0 {
+		for _, p := range percentiles {
+			b.Percentiles = append(b.Percentiles, Percentile{p, s.latency.percentile(p)})
+		}
+	}
+	return b
+}
+
+func (s *stats) String() string {
+	if s.latency.count() == 0 {
+		return fmt.Sprintf("0 ops, %d errors", s.errors)
+	}
+	return fmt.Sprintf("%d ops, %0.3f MB, %d errors, p50 %0.3f s, p99 %0.3f s, max %0.3f s",
+		s.latency.count(), float64(s.bytes)/(1024*1024), s.errors,
+		s.latency.percentile(50), s.latency.percentile(99), s.latency.percentile(100))
+}
+
+// statsFor returns the stats of the named group, creating them if needed
+func statsFor(groups map[string]*stats, name string, precision int) *stats {
+	s, ok := groups[name]
+	if !ok {
+		s = newStats(precision)
+		groups[name] = s
+	}
+	return s
+}
+
+// breakdowns returns the results of every group sorted by name
+func breakdowns(groups map[string]*stats, duration time.Duration) []Breakdown {
+	var result []Breakdown
+	for _, name := range sortedNames(groups) {
+		result = append(result, groups[name].breakdown(name, duration))
+	}
+	return result
+}
+
+func sortedNames(groups map[string]*stats) []string {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,204 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// defaultSweepMinGain is the throughput increase, in percent, below which
+// adding clients is considered to no longer scale
+const defaultSweepMinGain = 5
+
+// SweepResult holds the steps of a concurrency sweep and where the
+// service saturated
+type SweepResult struct {
+	Operation string      `json:"operation"`
+	Steps     []SweepStep `json:"steps"`
+	// Knee is the last number of clients before throughput stopped
+	// scaling, or the one at which p99 latency exceeded its threshold, or
+	// 0 if neither happened
+	Knee       uint   `json:"knee,omitempty"`
+	KneeReason string `json:"kneeReason"`
+}
+
+// SweepStep summarizes the run of a sweep with a given number of clients
+type SweepStep struct {
+	Clients    uint    `json:"numClients"`
+	Throughput float64 `json:"throughputMBps"`
+	OpsPerSec  float64 `json:"opsPerSecond"`
+	P50        float64 `json:"p50Seconds"`
+	P99        float64 `json:"p99Seconds"`
+	Errors     int     `json:"errors"`
+}
+
+// parseSweep parses either a comma separated list of client counts such as
+// "1,8,32" or a range such as "1-256", which doubles the clients at every
+// step
+func parseSweep(s string) ([]uint, error) {
+	var steps []uint
+	if bounds := strings.SplitN(s, "-", 2); len(bounds) == 2 {
+		from, err1 := strconv.ParseUint(strings.TrimSpace(bounds[0]), 10, 32)
+		to, err2 := strconv.ParseUint(strings.TrimSpace(bounds[1]), 10, 32)
+		if err1 != nil || err2 != nil || from < 1 || to < from {
+			return nil, fmt.Errorf("sweep(%s) needs a range of clients such as 1-256", s)
+		}
+		for n := from; n < to; n *= 2 {
+			steps = append(steps, uint(n))
+		}
+		return append(steps, uint(to)), nil
+	}
+	for _, item := range strings.Split(s, ",") {
+		n, err := strconv.ParseUint(strings.TrimSpace(item), 10, 32)
+		if err != nil || n < 1 || (len(steps) > 0 && uint(n) <= steps[len(steps)-1]) {
+			return nil, fmt.Errorf("sweep(%s) needs increasing numbers of clients such as 1,8,32", s)
+		}
+		steps = append(steps, uint(n))
+	}
+	return steps, nil
+}
+
+func (conf *Config) validateSweep() error {
+	if len(conf.Stages) > 0 {
+		return fmt.Errorf("sweep(%s) can't be combined with stages", conf.Sweep)
+	}
+	clients, err := parseSweep(conf.Sweep)
+	if err != nil {
+		return err
+	}
+	if max := clients[len(clients)-1]; max > conf.ObjectCount {
+		return fmt.Errorf("sweep(%s) needs at most numSamples(%d) clients", conf.Sweep, conf.ObjectCount)
+	}
+	if conf.SweepOp == "" {
+		conf.SweepOp = "read"
+	}
+	conf.SweepOp = strings.ToLower(conf.SweepOp)
+	if op, ok := stageOps[conf.SweepOp]; !ok || conf.SweepOp == prepareStage {
+		return fmt.Errorf("sweepOp(%s) needs to be one of write, read, head, list, copy, mixed or delete", conf.SweepOp)
+	} else if op == mixedOp && conf.Mix == "" {
+		return fmt.Errorf("sweepOp(%s) needs a mix", conf.SweepOp)
+	}
+	if conf.SweepMinGain == 0 {
+		conf.SweepMinGain = defaultSweepMinGain
+	}
+	return nil
+}
+
+// runSweep repeats the sweep operation with every number of clients of the
+// sweep. Objects are written beforehand, and again before every step of a
+// delete sweep, without being reported.
+func (r *Runner) runSweep(awsCfg *aws.Config) ([]report, *SweepResult, error) {
+	base := r.conf
+	defer func() { r.conf = base }()
+	clients, err := parseSweep(base.Sweep)
+	if err != nil {
+		return nil, nil, err
+	}
+	op := stageOps[base.SweepOp]
+	if err := r.loadSizes(); err != nil {
+		return nil, nil, err
+	}
+	var reports []report
+	sweep := &SweepResult{Operation: op}
+	for i, n := range clients {
+		if op != writeOp && (i == 0 || op == deleteOp) {
+			r.conf = base
+			r.keys = base.ObjectCount
+			r.runClients(awsCfg, writeOp)
+		}
+		conf := *base
+		conf.Clients = n
+		r.conf = &conf
+		fmt.Fprintf(r.out, "Sweep step %d: %d clients\n", i+1, n)
+		report := r.runClients(awsCfg, op)
+		report.Operation = fmt.Sprintf("%s-%d-clients", op, n)
+		reports = append(reports, report)
+		sweep.Steps = append(sweep.Steps, report.sweepStep(n))
+	}
+	sweep.findKnee(base.SweepMinGain, base.SweepMaxP99)
+	r.cleanup(awsCfg)
+	return reports, sweep, nil
+}
+
+func (r report) sweepStep(clients uint) SweepStep {
+	step := SweepStep{
+		Clients:    clients,
+		Throughput: (float64(r.bytesTransmitted) / (1024 * 1024)) / r.totalDuration.Seconds(),
+		OpsPerSec:  float64(r.count()) / r.totalDuration.Seconds(),
+		Errors:     r.numErrors,
+	}
+	if r.count() > 0 {
+		step.P50 = r.latency.percentile(50)
+		step.P99 = r.latency.percentile(99)
+	}
+	return step
+}
+
+// findKnee looks for the first step whose operation rate grew by less than
+// minGain percent over the previous step, which makes the previous step the
+// knee, or whose p99 exceeded maxP99
+func (s *SweepResult) findKnee(minGain float64, maxP99 time.Duration) {
+	for i, step := range s.Steps {
+		if maxP99 > 0 && step.P99 > maxP99.Seconds() {
+			s.Knee = step.Clients
+			s.KneeReason = fmt.Sprintf("p99 of %0.3f s exceeds %s", step.P99, maxP99)
+			return
+		}
+		if i == 0 {
+			continue
+		}
+		previous := s.Steps[i-1]
+		if previous.OpsPerSec == 0 {
+			continue
+		}
+		gain := 100 * (step.OpsPerSec - previous.OpsPerSec) / previous.OpsPerSec
+		if gain < minGain {
+			s.Knee = previous.Clients
+			s.KneeReason = fmt.Sprintf("throughput grows by %+0.1f%% from %d to %d clients", gain, previous.Clients, step.Clients)
+			return
+		}
+	}
+	s.KneeReason = fmt.Sprintf("throughput kept scaling up to %d clients", s.Steps[len(s.Steps)-1].Clients)
+}
+
+// write prints the steps of the sweep as a table followed by the knee
+func (s *SweepResult) write(w io.Writer) {
+	fmt.Fprintf(w, "Concurrency sweep of %s operations\n", s.Operation)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Clients\tMB/s\tops/s\tp50 (s)\tp99 (s)\tErrors\t")
+	for _, step := range s.Steps {
+		fmt.Fprintf(tw, "%d\t%0.2f\t%0.1f\t%0.3f\t%0.3f\t%d\t\n",
+			step.Clients, step.Throughput, step.OpsPerSec, step.P50, step.P99, step.Errors)
+	}
+	tw.Flush()
+	if s.Knee > 0 {
+		fmt.Fprintf(w, "Saturation at %d clients: %s\n", s.Knee, s.KneeReason)
+	} else {
+		fmt.Fprintf(w, "Saturation not reached: %s\n", s.KneeReason)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright © 2018 Giacomo Guiulfo <giacomoguiulfo@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bench
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSweep(t *testing.T) {
+	tests := []struct {
+		sweep string
+		want  []uint
+		error bool
+	}{
+		{"1-256", []uint{1, 2, 4, 8, 16, 32, 64, 128, 256}, false},
+		{"3-20", []uint{3, 6, 12, 20}, false},
+		{"8-8", []uint{8}, false},
+		{"1,8,32", []uint{1, 8, 32}, false},
+		{" 4 , 16 ", []uint{4, 16}, false},
+		{"16", []uint{16}, false},
+		{"0-8", nil, true},
+		{"8-1", nil, true},
+		{"1-x", nil, true},
+		{"8,4", nil, true},
+		{"1,1", nil, true},
+		{"0,4", nil, true},
+		{"", nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.sweep, func(t *testing.T) {
+			steps, err := parseSweep(test.sweep)
+			if test.error {
+				if err == nil {
+					t.Errorf("parseSweep(%q) = %v, want an error", test.sweep, steps)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(steps, test.want) {
+				t.Errorf("parseSweep(%q) = %v, %v, want %v", test.sweep, steps, err, test.want)
+			}
+		})
+	}
+}
+
+func TestFindKnee(t *testing.T) {
+	steps := func(opsAndP99 ...float64) []SweepStep {
+		var s []SweepStep
+		for i := 0; i < len(opsAndP99); i += 2 {
+			s = append(s, SweepStep{Clients: 1 << uint(i/2), OpsPerSec: opsAndP99[i], P99: opsAndP99[i+1]})
+		}
+		return s
+	}
+	tests := []struct {
+		name    string
+		steps   []SweepStep
+		minGain float64
+		maxP99  time.Duration
+		knee    uint
+	}{
+		{"scaling", steps(100, 0.1, 200, 0.1, 390, 0.1), 5, 0, 0},
+		{"flat", steps(100, 0.1, 200, 0.1, 204, 0.2, 206, 0.4), 5, 0, 2},
+		{"dropping", steps(100, 0.1, 90, 0.3), 5, 0, 1},
+		{"higher min gain", steps(100, 0.1, 200, 0.1, 250, 0.1), 50, 0, 2},
+		{"p99", steps(100, 0.1, 200, 0.2, 400, 0.6), 5, 500 * time.Millisecond, 4},
+		{"p99 of the first step", steps(100, 1, 200, 1), 5, 500 * time.Millisecond, 1},
+		{"idle step", steps(0, 0, 100, 0.1, 200, 0.1), 5, 0, 0},
+		{"single step", steps(100, 0.1), 5, 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sweep := SweepResult{Steps: test.steps}
+			sweep.findKnee(test.minGain, test.maxP99)
+			if sweep.Knee != test.knee {
+				t.Errorf("findKnee() = %d (%s), want %d", sweep.Knee, sweep.KneeReason, test.knee)
+			}
+			if sweep.KneeReason == "" {
+				t.Errorf("findKnee() gave no reason")
+			}
+		})
+	}
+}